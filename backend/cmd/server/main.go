@@ -19,11 +19,18 @@
 // @schemes http https
 // @contact.name VidToGallery API Support
 // @contact.email support@vidtogallery.com
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
@@ -33,6 +40,9 @@ import (
 	"vidtogallery/pkg/cache"
 	"vidtogallery/pkg/config"
 	"vidtogallery/pkg/downloader"
+	"vidtogallery/pkg/events"
+	"vidtogallery/pkg/jobs"
+	"vidtogallery/pkg/webhook"
 )
 
 func main() {
@@ -59,7 +69,17 @@ func main() {
 	defer cacheService.Close()
 
 	// Initialize downloader service
-	downloaderService := downloader.NewService(cfg.Download.MaxConcurrent, cfg, cacheService)
+	downloaderService := downloader.NewService(cfg.Download.MaxConcurrent, cfg, cacheService, logger)
+
+	// Initialize progress event broker, webhook notifier and job manager
+	broker := events.NewBroker(cfg.Jobs.EventRetention)
+	notifier := webhook.NewNotifier(cfg, cacheService, logger)
+	jobManager := jobs.NewManager(downloaderService, cacheService, broker, notifier, cfg, logger)
+
+	// Pick up jobs a previous instance left queued when it shut down
+	if err := jobManager.Resume(context.Background()); err != nil {
+		logger.WithError(err).Warn("Failed to resume queued jobs")
+	}
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -78,13 +98,66 @@ func main() {
 	})
 
 	// Setup routes
-	api.SetupRoutes(app, downloaderService, logger)
+	api.SetupRoutes(app, cfg, downloaderService, cacheService, jobManager, broker, notifier, logger)
 
 	// Start server
 	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
 	logger.WithField("address", addr).Info("Starting server")
 
-	if err := app.Listen(addr); err != nil {
-		logger.WithError(err).Fatal("Failed to start server")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(addr)
+	}()
+
+	select {
+	case err := <-listenErr:
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to start server")
+		}
+		return
+	case <-ctx.Done():
+	}
+	stop()
+
+	shutdown(app, downloaderService, jobManager, broker, notifier, cfg, logger)
+}
+
+// shutdown stops accepting requests and drains in-flight work until the
+// shutdown timeout, after which whatever is left is cancelled. Running jobs
+// are persisted as queued so the next instance resumes them.
+func shutdown(app *fiber.App, downloaderService *downloader.Service, jobManager *jobs.Manager, broker *events.Broker, notifier *webhook.Notifier, cfg *config.Config, logger *logrus.Logger) {
+	logger.WithField("timeout", cfg.Server.ShutdownTimeout).Info("Shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	// Closing the listener first rejects new connections while open requests,
+	// including proxy streams, run to completion
+	httpDone := make(chan error, 1)
+	go func() {
+		httpDone <- app.ShutdownWithContext(ctx)
+	}()
+
+	if err := jobManager.Shutdown(ctx); err != nil {
+		logger.WithError(err).Warn("Jobs did not finish before the shutdown deadline")
 	}
+
+	// Job and download event streams never finish on their own once their
+	// work has stopped, so end them to let the HTTP server drain
+	broker.CloseAll()
+
+	if err := downloaderService.Shutdown(ctx); err != nil {
+		logger.WithError(err).Warn("Extractions and downloads did not finish before the shutdown deadline")
+	}
+	if err := <-httpDone; err != nil {
+		logger.WithError(err).Warn("HTTP server did not shut down cleanly")
+	}
+	if err := notifier.Shutdown(ctx); err != nil {
+		logger.WithError(err).Warn("Webhook deliveries did not finish before the shutdown deadline")
+	}
+
+	logger.Info("Server stopped")
 }
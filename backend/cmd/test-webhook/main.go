@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"vidtogallery/pkg/config"
+	"vidtogallery/pkg/webhook"
+)
+
+// maxClockSkew is how old a signed timestamp may be before it is rejected as a replay
+const maxClockSkew = 5 * time.Minute
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9090", "address to listen on")
+	fail := flag.Int("fail", 0, "answer the first N deliveries with HTTP 500 to exercise retries")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+	if cfg.Webhooks.Secret == "" {
+		log.Fatal("WEBHOOK_SECRET must be set to verify signatures")
+	}
+	secret := []byte(cfg.Webhooks.Secret)
+
+	var received atomic.Int64
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		n := received.Add(1)
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		timestamp := r.Header.Get(webhook.HeaderTimestamp)
+		signature := r.Header.Get(webhook.HeaderSignature)
+		fmt.Printf("Delivery #%d: event=%s id=%s\n", n, r.Header.Get(webhook.HeaderEvent), r.Header.Get(webhook.HeaderDelivery))
+
+		if !webhook.Verify(secret, timestamp, body, signature) {
+			fmt.Println("  Signature: INVALID")
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if sent, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(sent, 0)) > maxClockSkew {
+			fmt.Println("  Signature: valid, but timestamp is too old")
+			http.Error(w, "stale timestamp", http.StatusUnauthorized)
+			return
+		}
+		fmt.Println("  Signature: valid")
+
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, body, "  ", "  "); err == nil {
+			fmt.Printf("  %s\n", pretty.String())
+		}
+
+		if n <= int64(*fail) {
+			fmt.Println("  Responding 500 (simulated failure)")
+			http.Error(w, "simulated failure", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	fmt.Printf("Listening for webhooks on http://%s/\n", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -18,9 +18,66 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/download": {
+        "/api/v1/admin/cache/entry": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Look up every cached extraction and file mapping for a source URL",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Inspect cache entries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Source URL of the post",
+                        "name": "url",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cache entries",
+                        "schema": {
+                            "$ref": "#/definitions/models.CacheLookupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/cache/purge": {
             "post": {
-                "description": "Download video from social media platform with specified quality",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Delete cached entries for a source or video URL, for a whole platform, or by key prefix (e.g. \"video_file:\")",
                 "consumes": [
                     "application/json"
                 ],
@@ -28,25 +85,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Video Processing"
+                    "Admin"
                 ],
-                "summary": "Download video with quality",
+                "summary": "Purge cache entries",
                 "parameters": [
                     {
-                        "description": "Video URL and quality to download",
+                        "description": "Exactly one of url, platform or prefix",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.VideoRequest"
+                            "$ref": "#/definitions/models.CachePurgeRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Video downloaded successfully",
+                        "description": "Number of deleted keys",
                         "schema": {
-                            "$ref": "#/definitions/models.VideoResponse"
+                            "$ref": "#/definitions/models.CachePurgeResponse"
                         }
                     },
                     "400": {
@@ -55,6 +112,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -64,35 +127,175 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/proxy-download": {
+        "/api/v1/admin/cache/stats": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Report hit ratios, key counts and memory usage per cache namespace and disk cache usage",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Cache statistics",
+                "responses": {
+                    "200": {
+                        "description": "Cache statistics",
+                        "schema": {
+                            "$ref": "#/definitions/models.CacheStatsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/webhooks/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "List recent webhook deliveries with every attempt, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Webhook delivery log",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of deliveries (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Recent deliveries",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookDeliveriesResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/webhooks/deliveries/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Get a webhook delivery with every attempt",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Webhook delivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Delivery ID (sent as the X-VidToGallery-Delivery header)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Delivery",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookDelivery"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Delivery not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/batch": {
             "post": {
-                "description": "Download video file through backend proxy to avoid CORS restrictions",
+                "description": "Extract video URLs for a list of posts concurrently, each with its own quality. Duplicate posts (by canonical URL and quality) are extracted once. Every item gets a result or an error; one failure does not abort the batch. With a callback_url on an allowed host the batch runs in the background: the response is 202 with the batch ID and the results are POSTed as a signed webhook.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/octet-stream"
+                    "application/json"
                 ],
                 "tags": [
                     "Video Processing"
                 ],
-                "summary": "Proxy download video file",
+                "summary": "Batch download videos",
                 "parameters": [
                     {
-                        "description": "Video URL to proxy download",
+                        "description": "Video URLs and qualities to download",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.ProxyDownloadRequest"
+                            "$ref": "#/definitions/models.BatchRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Video file",
+                        "description": "Per-item results",
                         "schema": {
-                            "type": "file"
+                            "$ref": "#/definitions/models.BatchResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Batch accepted for a callback",
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchResponse"
                         }
                     },
                     "400": {
@@ -101,8 +304,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "503": {
+                        "description": "Server is shutting down",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -110,9 +313,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/qualities": {
+        "/api/v1/download": {
             "post": {
-                "description": "Get list of available video qualities for a social media URL",
+                "description": "Download video from social media platform with specified quality. With quality \"auto\" the quality is chosen from the Save-Data, ECT, Downlink, Viewport-Width and DPR client hints and the User-Agent, and metadata.quality_reason explains the choice.",
                 "consumes": [
                     "application/json"
                 ],
@@ -122,27 +325,27 @@ const docTemplate = `{
                 "tags": [
                     "Video Processing"
                 ],
-                "summary": "Get available video qualities",
+                "summary": "Download video with quality",
                 "parameters": [
                     {
-                        "description": "Video URL to check qualities for",
+                        "description": "Video URL and quality to download",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.QualityRequest"
+                            "$ref": "#/definitions/models.VideoRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Available qualities retrieved successfully",
+                        "description": "Video downloaded successfully",
                         "schema": {
-                            "$ref": "#/definitions/models.QualitiesResponse"
+                            "$ref": "#/definitions/models.VideoResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid request",
+                        "description": "Invalid request or quality",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -152,84 +355,808 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "All workers are busy and the queue is full; retry after Retry-After seconds",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/health": {
-            "get": {
-                "description": "Check if the API is running and healthy",
+        "/api/v1/jobs": {
+            "post": {
+                "description": "Start an extraction (\"extract\") or extraction plus file download (\"download\") in the background and return its ID immediately. With a callback_url on an allowed host, a signed webhook is POSTed when the job finishes.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Health"
+                    "Jobs"
+                ],
+                "summary": "Create job",
+                "parameters": [
+                    {
+                        "description": "Job type, video URL and quality",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.JobRequest"
+                        }
+                    }
                 ],
-                "summary": "Health check endpoint",
                 "responses": {
-                    "200": {
-                        "description": "API is healthy",
+                    "202": {
+                        "description": "Job accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Server is shutting down",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "code": {
-                    "type": "string"
-                },
-                "details": {
-                    "type": "string"
-                },
-                "error": {
-                    "type": "string"
-                }
-            }
-        },
-        "models.ProxyDownloadRequest": {
-            "type": "object",
-            "required": [
-                "video_url"
-            ],
-            "properties": {
-                "video_url": {
-                    "type": "string"
-                }
-            }
         },
-        "models.QualitiesResponse": {
-            "type": "object",
-            "properties": {
-                "available_qualities": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.QualityOption"
+        "/api/v1/jobs/{id}": {
+            "get": {
+                "description": "Get the state, progress and, once finished, the result of a job",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Get job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
-                },
-                "platform": {
-                    "type": "string"
-                }
-            }
-        },
-        "models.QualityOption": {
-            "type": "object",
-            "properties": {
-                "height": {
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Job state",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Cancel a job that has not finished yet",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Cancel job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cancelled job",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Job already finished",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/jobs/{id}/events": {
+            "get": {
+                "description": "Stream stage changes and byte progress of a job as Server-Sent Events. \"progress\" events carry a models.Progress, the final \"done\" event carries the finished models.Job. Reconnecting clients resume after the Last-Event-ID header (or last_event_id query parameter).",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Stream job progress",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the last event received",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the last event received, for clients that cannot set headers",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/proxy-download": {
+            "post": {
+                "description": "Download video file through backend proxy to avoid CORS restrictions",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "Video Processing"
+                ],
+                "summary": "Proxy download video file",
+                "parameters": [
+                    {
+                        "description": "Video URL to proxy download, optionally with its source post, quality and a progress ID to follow it by",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ProxyDownloadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Video file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Cached file matches If-None-Match"
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "All workers are busy and the queue is full; retry after Retry-After seconds",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/proxy-download/{progress_id}/events": {
+            "get": {
+                "description": "Stream byte progress of a proxy download started with the same progress_id as Server-Sent Events. Subscribing before the download starts is allowed. \"progress\" and \"done\" events carry a models.Progress, an \"error\" event carries a models.ErrorResponse. Reconnecting clients resume after the Last-Event-ID header (or last_event_id query parameter).",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Video Processing"
+                ],
+                "summary": "Stream proxy download progress",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Progress ID sent with the proxy download request",
+                        "name": "progress_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the last event received",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the last event received, for clients that cannot set headers",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/qualities": {
+            "post": {
+                "description": "Get list of available video qualities for a social media URL",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Video Processing"
+                ],
+                "summary": "Get available video qualities",
+                "parameters": [
+                    {
+                        "description": "Video URL to check qualities for",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.QualityRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Available qualities retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.QualitiesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "All workers are busy and the queue is full; retry after Retry-After seconds",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/quality-profiles": {
+            "get": {
+                "description": "List the server-defined quality profiles. A profile name can be passed wherever a quality selector is accepted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Video Processing"
+                ],
+                "summary": "List quality profiles",
+                "responses": {
+                    "200": {
+                        "description": "Configured quality profiles",
+                        "schema": {
+                            "$ref": "#/definitions/models.QualityProfilesResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Check if the API is running and healthy, with busy workers and queue depth of the extraction and proxy download pools and the yt-dlp version in use",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Health check endpoint",
+                "responses": {
+                    "200": {
+                        "description": "API is healthy",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.BatchItem": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "quality": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BatchItemResult": {
+            "type": "object",
+            "properties": {
+                "canonical_url": {
+                    "type": "string"
+                },
+                "duplicate_of": {
+                    "description": "DuplicateOf is the index of the earlier item this one was collapsed into",
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "quality": {
+                    "type": "string"
+                },
+                "result": {
+                    "$ref": "#/definitions/models.VideoResponse"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BatchRequest": {
+            "type": "object",
+            "required": [
+                "items"
+            ],
+            "properties": {
+                "callback_url": {
+                    "description": "CallbackURL makes the batch run in the background and deliver its results as a signed webhook",
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BatchItem"
+                    }
+                }
+            }
+        },
+        "models.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "ID identifies a batch running in the background for a callback",
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BatchItemResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CacheDiskStats": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "files": {
+                    "type": "integer"
+                },
+                "max_bytes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CacheEntry": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "extractor": {
+                    "type": "string"
+                },
+                "file": {
+                    "$ref": "#/definitions/models.VideoFile"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "schema_version": {
+                    "type": "integer"
+                },
+                "ttl_seconds": {
+                    "type": "integer"
+                },
+                "video": {
+                    "$ref": "#/definitions/models.VideoResponse"
+                }
+            }
+        },
+        "models.CacheLookupResponse": {
+            "type": "object",
+            "properties": {
+                "canonical_url": {
+                    "type": "string"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CacheEntry"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CacheNamespaceStats": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "hit_ratio": {
+                    "type": "number"
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "keys": {
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CachePurgeRequest": {
+            "type": "object",
+            "properties": {
+                "platform": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CachePurgeResponse": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CacheStatsResponse": {
+            "type": "object",
+            "properties": {
+                "disk": {
+                    "$ref": "#/definitions/models.CacheDiskStats"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "namespaces": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.CacheNamespaceStats"
+                    }
+                },
+                "schema_version": {
+                    "type": "integer"
+                },
+                "version_mismatches": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "pools": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.PoolStats"
+                    }
+                },
+                "service": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "ytdlp": {
+                    "$ref": "#/definitions/models.YtDlpStatus"
+                }
+            }
+        },
+        "models.Job": {
+            "type": "object",
+            "properties": {
+                "callback_url": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "file": {
+                    "$ref": "#/definitions/models.VideoFile"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "progress": {
+                    "$ref": "#/definitions/models.Progress"
+                },
+                "quality": {
+                    "type": "string"
+                },
+                "quality_reason": {
+                    "type": "string"
+                },
+                "result": {
+                    "$ref": "#/definitions/models.VideoResponse"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.JobRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "callback_url": {
+                    "description": "CallbackURL receives a signed webhook when the job finishes",
+                    "type": "string"
+                },
+                "quality": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PoolStats": {
+            "type": "object",
+            "properties": {
+                "busy": {
+                    "type": "integer"
+                },
+                "queue_size": {
+                    "type": "integer"
+                },
+                "queued": {
+                    "type": "integer"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Progress": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "message": {
+                    "description": "Message is the latest status message while extracting",
+                    "type": "string"
+                },
+                "stage": {
+                    "type": "string"
+                },
+                "total_bytes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ProxyDownloadRequest": {
+            "type": "object",
+            "required": [
+                "video_url"
+            ],
+            "properties": {
+                "progress_id": {
+                    "description": "ProgressID lets the client follow this download on /api/v1/proxy-download/{progress_id}/events",
+                    "type": "string"
+                },
+                "quality": {
+                    "type": "string"
+                },
+                "source_url": {
+                    "type": "string"
+                },
+                "video_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.QualitiesResponse": {
+            "type": "object",
+            "properties": {
+                "available_qualities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.QualityOption"
+                    }
+                },
+                "platform": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.QualityOption": {
+            "type": "object",
+            "properties": {
+                "acodec": {
+                    "type": "string"
+                },
+                "aspect_ratio": {
+                    "description": "AspectRatio is width divided by height, e.g. 0.562 for 9:16",
+                    "type": "number"
+                },
+                "bitrate": {
+                    "description": "Bitrate is the total bitrate in kbit/s",
+                    "type": "number"
+                },
+                "container": {
+                    "type": "string"
+                },
+                "filesize": {
+                    "description": "FileSize is exact when yt-dlp knows it, otherwise estimated from the bitrate",
+                    "type": "integer"
+                },
+                "fps": {
+                    "type": "number"
+                },
+                "has_audio": {
+                    "type": "boolean"
+                },
+                "hdr": {
+                    "type": "boolean"
+                },
+                "height": {
                     "type": "integer"
                 },
                 "label": {
                     "type": "string"
                 },
+                "orientation": {
+                    "description": "Orientation is \"landscape\", \"portrait\" or \"square\"",
+                    "type": "string"
+                },
                 "quality": {
                     "type": "string"
                 },
+                "vcodec": {
+                    "type": "string"
+                },
                 "video_url": {
                     "type": "string"
                 },
@@ -238,6 +1165,35 @@ const docTemplate = `{
                 }
             }
         },
+        "models.QualityProfile": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "quality": {
+                    "description": "Quality is the selector the profile resolves to",
+                    "type": "string"
+                }
+            }
+        },
+        "models.QualityProfilesResponse": {
+            "type": "object",
+            "properties": {
+                "profiles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.QualityProfile"
+                    }
+                }
+            }
+        },
         "models.QualityRequest": {
             "type": "object",
             "required": [
@@ -249,6 +1205,20 @@ const docTemplate = `{
                 }
             }
         },
+        "models.VideoFile": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
         "models.VideoRequest": {
             "type": "object",
             "required": [
@@ -297,6 +1267,101 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "models.WebhookAttempt": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WebhookDeliveriesResponse": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookDelivery"
+                    }
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookAttempt"
+                    }
+                },
+                "batch_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "job_id": {
+                    "type": "string"
+                },
+                "next_attempt": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.YtDlpStatus": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Error explains why the binary is not used, e.g. it is too old",
+                    "type": "string"
+                },
+                "min_version": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                },
+                "worker_version": {
+                    "description": "WorkerVersion is the yt-dlp version the worker processes imported last",
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// VideoFile is the index record for a video file stored in the disk cache.
+// Files are content-addressed: Hash is the hex SHA-256 of the file bytes.
+type VideoFile struct {
+	Hash      string    `json:"hash"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CacheNamespaceStats struct {
+	Keys     int64   `json:"keys"`
+	Bytes    int64   `json:"bytes"`
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+type CacheDiskStats struct {
+	Files    int   `json:"files"`
+	Bytes    int64 `json:"bytes"`
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+type CacheStatsResponse struct {
+	Enabled           bool                           `json:"enabled"`
+	Namespaces        map[string]CacheNamespaceStats `json:"namespaces"`
+	Disk              *CacheDiskStats                `json:"disk,omitempty"`
+	SchemaVersion     int                            `json:"schema_version"`
+	VersionMismatches int64                          `json:"version_mismatches"`
+}
+
+type CacheEntry struct {
+	Key           string         `json:"key"`
+	TTLSeconds    int64          `json:"ttl_seconds"`
+	SchemaVersion int            `json:"schema_version,omitempty"`
+	Extractor     string         `json:"extractor,omitempty"`
+	CreatedAt     *time.Time     `json:"created_at,omitempty"`
+	Video         *VideoResponse `json:"video,omitempty"`
+	File          *VideoFile     `json:"file,omitempty"`
+}
+
+type CacheLookupResponse struct {
+	URL          string       `json:"url"`
+	CanonicalURL string       `json:"canonical_url"`
+	Entries      []CacheEntry `json:"entries"`
+}
+
+type CachePurgeRequest struct {
+	URL      string `json:"url,omitempty"`
+	Platform string `json:"platform,omitempty"`
+	Prefix   string `json:"prefix,omitempty"`
+}
+
+type CachePurgeResponse struct {
+	Deleted int64 `json:"deleted"`
+}
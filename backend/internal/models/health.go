@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// PoolStats describes the load on one worker pool
+type PoolStats struct {
+	Workers   int `json:"workers"`
+	Busy      int `json:"busy"`
+	Queued    int `json:"queued"`
+	QueueSize int `json:"queue_size"`
+}
+
+// YtDlpStatus describes the yt-dlp binary in use
+type YtDlpStatus struct {
+	Path       string `json:"path"`
+	Version    string `json:"version,omitempty"`
+	MinVersion string `json:"min_version,omitempty"`
+	// WorkerVersion is the yt-dlp version the worker processes imported last
+	WorkerVersion string `json:"worker_version,omitempty"`
+	// Error explains why the binary is not used, e.g. it is too old
+	Error string `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	Status    string               `json:"status"`
+	Timestamp time.Time            `json:"timestamp"`
+	Service   string               `json:"service"`
+	Pools     map[string]PoolStats `json:"pools"`
+	YtDlp     YtDlpStatus          `json:"ytdlp"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Job types
+const (
+	JobTypeExtract  = "extract"
+	JobTypeDownload = "download"
+)
+
+// Job states
+const (
+	JobStateQueued    = "queued"
+	JobStateRunning   = "running"
+	JobStateSucceeded = "succeeded"
+	JobStateFailed    = "failed"
+	JobStateCancelled = "cancelled"
+)
+
+// Processing stages reported while a job runs
+const (
+	StageQueued     = "queued"
+	StageExtracting = "extracting"
+	StageFetching   = "fetching"
+	StageDone       = "done"
+)
+
+type Progress struct {
+	Stage      string `json:"stage"`
+	Bytes      int64  `json:"bytes,omitempty"`
+	TotalBytes int64  `json:"total_bytes,omitempty"`
+	// Message is the latest status message while extracting
+	Message string `json:"message,omitempty"`
+}
+
+type JobRequest struct {
+	Type    string `json:"type,omitempty"`
+	URL     string `json:"url" validate:"required"`
+	Quality string `json:"quality,omitempty"`
+	// CallbackURL receives a signed webhook when the job finishes
+	CallbackURL string `json:"callback_url,omitempty"`
+	// QualityReason is why the auto quality chose Quality, set by the API
+	QualityReason string `json:"-"`
+}
+
+type Job struct {
+	ID            string         `json:"id"`
+	Type          string         `json:"type"`
+	State         string         `json:"state"`
+	URL           string         `json:"url"`
+	Quality       string         `json:"quality"`
+	QualityReason string         `json:"quality_reason,omitempty"`
+	CallbackURL   string         `json:"callback_url,omitempty"`
+	Progress      Progress       `json:"progress"`
+	Result        *VideoResponse `json:"result,omitempty"`
+	File          *VideoFile     `json:"file,omitempty"`
+	Error         string         `json:"error,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// Finished reports whether the job has reached a terminal state
+func (j *Job) Finished() bool {
+	switch j.State {
+	case JobStateSucceeded, JobStateFailed, JobStateCancelled:
+		return true
+	}
+	return false
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"io"
+	"time"
+)
 
 type VideoRequest struct {
 	URL     string `json:"url" validate:"required"`
@@ -19,10 +22,24 @@ type VideoResponse struct {
 }
 
 type QualityOption struct {
-	Quality  string `json:"quality"`
-	Label    string `json:"label"`
-	Width    int    `json:"width,omitempty"`
-	Height   int    `json:"height,omitempty"`
+	Quality string `json:"quality"`
+	Label   string `json:"label"`
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+	// Orientation is "landscape", "portrait" or "square"
+	Orientation string `json:"orientation,omitempty"`
+	// AspectRatio is width divided by height, e.g. 0.562 for 9:16
+	AspectRatio float64 `json:"aspect_ratio,omitempty"`
+	FPS         float64 `json:"fps,omitempty"`
+	VCodec      string  `json:"vcodec,omitempty"`
+	ACodec      string  `json:"acodec,omitempty"`
+	Container   string  `json:"container,omitempty"`
+	// Bitrate is the total bitrate in kbit/s
+	Bitrate float64 `json:"bitrate,omitempty"`
+	// FileSize is exact when yt-dlp knows it, otherwise estimated from the bitrate
+	FileSize int64  `json:"filesize,omitempty"`
+	HDR      bool   `json:"hdr"`
+	HasAudio bool   `json:"has_audio"`
 	VideoURL string `json:"video_url"`
 }
 
@@ -31,6 +48,19 @@ type QualitiesResponse struct {
 	Platform           string          `json:"platform"`
 }
 
+// QualityProfile is a named quality that can be passed as the quality of a request
+type QualityProfile struct {
+	Name        string `json:"name"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	// Quality is the selector the profile resolves to
+	Quality string `json:"quality"`
+}
+
+type QualityProfilesResponse struct {
+	Profiles []QualityProfile `json:"profiles"`
+}
+
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Code    string `json:"code,omitempty"`
@@ -42,9 +72,46 @@ type QualityRequest struct {
 }
 
 type ProxyDownloadRequest struct {
-	VideoURL string `json:"video_url" validate:"required"`
+	VideoURL  string `json:"video_url" validate:"required"`
+	SourceURL string `json:"source_url,omitempty"`
+	Quality   string `json:"quality,omitempty"`
+	// ProgressID lets the client follow this download on /api/v1/proxy-download/{progress_id}/events
+	ProgressID string `json:"progress_id,omitempty"`
 }
 
 type ProxyDownloadResponse struct {
-	Data []byte `json:"-"`
+	Body        io.ReadCloser `json:"-"`
+	Size        int64         `json:"-"`
+	ContentHash string        `json:"-"`
+}
+
+type BatchItem struct {
+	URL     string `json:"url" validate:"required"`
+	Quality string `json:"quality,omitempty"`
+	// QualityReason is why the auto quality chose Quality, set by the API
+	QualityReason string `json:"-"`
+}
+
+type BatchRequest struct {
+	Items []BatchItem `json:"items" validate:"required"`
+	// CallbackURL makes the batch run in the background and deliver its results as a signed webhook
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+type BatchItemResult struct {
+	URL          string         `json:"url"`
+	Quality      string         `json:"quality"`
+	CanonicalURL string         `json:"canonical_url,omitempty"`
+	Result       *VideoResponse `json:"result,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	// DuplicateOf is the index of the earlier item this one was collapsed into
+	DuplicateOf *int `json:"duplicate_of,omitempty"`
+}
+
+type BatchResponse struct {
+	// ID identifies a batch running in the background for a callback
+	ID        string            `json:"id,omitempty"`
+	Results   []BatchItemResult `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
 }
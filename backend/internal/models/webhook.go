@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Webhook events
+const (
+	WebhookEventJobFinished   = "job.finished"
+	WebhookEventBatchFinished = "batch.finished"
+)
+
+// Webhook delivery states
+const (
+	DeliveryStatePending   = "pending"
+	DeliveryStateDelivered = "delivered"
+	DeliveryStateFailed    = "failed"
+)
+
+// WebhookPayload is the signed JSON body POSTed to a callback URL
+type WebhookPayload struct {
+	Event   string            `json:"event"`
+	JobID   string            `json:"job_id,omitempty"`
+	BatchID string            `json:"batch_id,omitempty"`
+	State   string            `json:"state,omitempty"`
+	Video   *VideoResponse    `json:"video,omitempty"`
+	File    *VideoFile        `json:"file,omitempty"`
+	Results []BatchItemResult `json:"results,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	SentAt  time.Time         `json:"sent_at"`
+}
+
+type WebhookAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// WebhookDelivery records every attempt to deliver one payload
+type WebhookDelivery struct {
+	ID          string           `json:"id"`
+	Event       string           `json:"event"`
+	URL         string           `json:"url"`
+	JobID       string           `json:"job_id,omitempty"`
+	BatchID     string           `json:"batch_id,omitempty"`
+	State       string           `json:"state"`
+	Attempts    []WebhookAttempt `json:"attempts"`
+	NextAttempt *time.Time       `json:"next_attempt,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
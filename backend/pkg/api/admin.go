@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"vidtogallery/internal/models"
+	"vidtogallery/pkg/cache"
+	"vidtogallery/pkg/downloader"
+)
+
+// AdminAuth rejects requests without the configured bearer token
+func AdminAuth(token string) fiber.Handler {
+	expected := []byte("Bearer " + token)
+
+	return func(c *fiber.Ctx) error {
+		provided := []byte(c.Get(fiber.HeaderAuthorization))
+		if subtle.ConstantTimeCompare(provided, expected) != 1 {
+			return c.Status(401).JSON(models.ErrorResponse{
+				Error: "Invalid or missing admin token",
+				Code:  "UNAUTHORIZED",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// CacheStats reports cache usage per namespace
+// @Summary Cache statistics
+// @Description Report hit ratios, key counts and memory usage per cache namespace and disk cache usage
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} models.CacheStatsResponse "Cache statistics"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/v1/admin/cache/stats [get]
+func (h *Handler) CacheStats(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+	defer cancel()
+
+	stats, err := h.cacheService.Stats(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to collect cache statistics")
+		return c.Status(500).JSON(models.ErrorResponse{
+			Error:   "Failed to collect cache statistics",
+			Code:    "CACHE_STATS_ERROR",
+			Details: err.Error(),
+		})
+	}
+
+	return c.JSON(stats)
+}
+
+// CacheLookup returns the cache entries for a source URL
+// @Summary Inspect cache entries
+// @Description Look up every cached extraction and file mapping for a source URL
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Param url query string true "Source URL of the post"
+// @Success 200 {object} models.CacheLookupResponse "Cache entries"
+// @Failure 400 {object} models.ErrorResponse "Invalid request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/v1/admin/cache/entry [get]
+func (h *Handler) CacheLookup(c *fiber.Ctx) error {
+	url := c.Query("url")
+	if url == "" {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "url is required",
+			Code:  "MISSING_URL",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+	defer cancel()
+
+	canonical := downloader.CanonicalURL(url)
+	entries, err := h.cacheService.SourceEntries(ctx, canonical)
+	if err != nil {
+		h.logger.WithError(err).WithField("url", url).Error("Failed to look up cache entries")
+		return c.Status(500).JSON(models.ErrorResponse{
+			Error:   "Failed to look up cache entries",
+			Code:    "CACHE_LOOKUP_ERROR",
+			Details: err.Error(),
+		})
+	}
+
+	return c.JSON(models.CacheLookupResponse{
+		URL:          url,
+		CanonicalURL: canonical,
+		Entries:      entries,
+	})
+}
+
+// CachePurge deletes cache entries by URL, platform or key prefix
+// @Summary Purge cache entries
+// @Description Delete cached entries for a source or video URL, for a whole platform, or by key prefix (e.g. "video_file:")
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param request body models.CachePurgeRequest true "Exactly one of url, platform or prefix"
+// @Success 200 {object} models.CachePurgeResponse "Number of deleted keys"
+// @Failure 400 {object} models.ErrorResponse "Invalid request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/v1/admin/cache/purge [post]
+func (h *Handler) CachePurge(c *fiber.Ctx) error {
+	var req models.CachePurgeRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to parse request body")
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+			Code:  "INVALID_REQUEST",
+		})
+	}
+
+	selectors := 0
+	for _, value := range []string{req.URL, req.Platform, req.Prefix} {
+		if value != "" {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Exactly one of url, platform or prefix is required",
+			Code:  "INVALID_PURGE_SELECTOR",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 60*time.Second)
+	defer cancel()
+
+	var deleted int64
+	var err error
+	switch {
+	case req.URL != "":
+		// The URL may be a source post or a direct CDN video URL
+		deleted, err = h.cacheService.PurgeSource(ctx, downloader.CanonicalURL(req.URL))
+		if err == nil {
+			var n int64
+			n, err = h.cacheService.PurgeVideoFile(ctx, strings.TrimSpace(req.URL))
+			deleted += n
+		}
+	case req.Platform != "":
+		platform := strings.ToLower(req.Platform)
+		deleted, err = h.cacheService.PurgeMatching(ctx, func(namespace string, id string) bool {
+			if namespace == cache.NamespaceVideoFile {
+				return h.downloaderService.DetectMediaPlatform(id) == platform
+			}
+			return h.downloaderService.DetectPlatform(id) == platform
+		})
+	default:
+		deleted, err = h.cacheService.PurgePrefix(ctx, req.Prefix)
+	}
+
+	if err != nil {
+		if errors.Is(err, cache.ErrInvalidPrefix) {
+			return c.Status(400).JSON(models.ErrorResponse{
+				Error:   "Invalid prefix",
+				Code:    "INVALID_PREFIX",
+				Details: err.Error(),
+			})
+		}
+		h.logger.WithError(err).Error("Failed to purge cache")
+		return c.Status(500).JSON(models.ErrorResponse{
+			Error:   "Failed to purge cache",
+			Code:    "CACHE_PURGE_ERROR",
+			Details: err.Error(),
+		})
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"url":      req.URL,
+		"platform": req.Platform,
+		"prefix":   req.Prefix,
+		"deleted":  deleted,
+	}).Info("Cache purged")
+
+	return c.JSON(models.CachePurgeResponse{Deleted: deleted})
+}
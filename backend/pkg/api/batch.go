@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"vidtogallery/internal/models"
+	"vidtogallery/pkg/downloader"
+)
+
+// maxBatchItems caps how many URLs a single batch request may contain
+const maxBatchItems = 50
+
+// batchTimeout bounds a whole batch
+const batchTimeout = 5 * time.Minute
+
+// BatchDownload extracts many videos in one request
+// @Summary Batch download videos
+// @Description Extract video URLs for a list of posts concurrently, each with its own quality. Duplicate posts (by canonical URL and quality) are extracted once. Every item gets a result or an error; one failure does not abort the batch. With a callback_url on an allowed host the batch runs in the background: the response is 202 with the batch ID and the results are POSTed as a signed webhook.
+// @Tags Video Processing
+// @Accept json
+// @Produce json
+// @Param request body models.BatchRequest true "Video URLs and qualities to download"
+// @Success 200 {object} models.BatchResponse "Per-item results"
+// @Success 202 {object} models.BatchResponse "Batch accepted for a callback"
+// @Failure 400 {object} models.ErrorResponse "Invalid request"
+// @Failure 503 {object} models.ErrorResponse "Server is shutting down"
+// @Router /api/v1/batch [post]
+func (h *Handler) BatchDownload(c *fiber.Ctx) error {
+	var req models.BatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to parse request body")
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+			Code:  "INVALID_REQUEST",
+		})
+	}
+
+	if len(req.Items) == 0 {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "items is required",
+			Code:  "MISSING_ITEMS",
+		})
+	}
+
+	if len(req.Items) > maxBatchItems {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error:   "Too many items",
+			Code:    "BATCH_TOO_LARGE",
+			Details: fmt.Sprintf("a batch may contain at most %d items", maxBatchItems),
+		})
+	}
+
+	for i := range req.Items {
+		req.Items[i].Quality, req.Items[i].QualityReason = h.autoQuality(c, req.Items[i].Quality)
+	}
+
+	if req.CallbackURL != "" {
+		if err := h.notifier.ValidateURL(req.CallbackURL); err != nil {
+			return h.callbackError(c, err)
+		}
+
+		// Results are delivered to the callback instead of holding the
+		// connection, so shutdown has to wait for the batch itself
+		ctx, done, err := h.downloaderService.Track(context.Background())
+		if err != nil {
+			return c.Status(503).JSON(models.ErrorResponse{
+				Error: "Server is shutting down",
+				Code:  "SHUTTING_DOWN",
+			})
+		}
+
+		id := uuid.NewString()
+		go func() {
+			defer done()
+			ctx, cancel := context.WithTimeout(ctx, batchTimeout)
+			defer cancel()
+
+			response := h.processBatch(ctx, id, req.Items)
+			h.notifier.Deliver(req.CallbackURL, models.WebhookPayload{
+				Event:   models.WebhookEventBatchFinished,
+				BatchID: id,
+				Results: response.Results,
+			})
+		}()
+
+		return c.Status(202).JSON(models.BatchResponse{
+			ID:      id,
+			Results: []models.BatchItemResult{},
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), batchTimeout)
+	defer cancel()
+
+	return c.JSON(h.processBatch(ctx, "", req.Items))
+}
+
+func (h *Handler) processBatch(ctx context.Context, id string, items []models.BatchItem) models.BatchResponse {
+	h.logger.WithField("batch_id", id).WithField("items", len(items)).Info("Processing batch")
+
+	results := h.downloaderService.ProcessBatch(ctx, items)
+	for i, item := range items {
+		if item.QualityReason != "" && results[i].Result != nil {
+			results[i].Result = downloader.WithQualityReason(results[i].Result, item.QualityReason)
+		}
+	}
+
+	response := models.BatchResponse{ID: id, Results: results}
+	for _, result := range results {
+		if result.Error != "" {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"batch_id":  id,
+		"items":     len(results),
+		"succeeded": response.Succeeded,
+		"failed":    response.Failed,
+	}).Info("Batch processed")
+
+	return response
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"vidtogallery/internal/models"
+	"vidtogallery/pkg/downloader"
+	"vidtogallery/pkg/events"
+)
+
+// sseHeartbeat is how often an idle event stream sends a comment so proxies
+// keep the connection open and disconnected clients are noticed
+const sseHeartbeat = 15 * time.Second
+
+// sseRetry is the reconnect delay suggested to EventSource clients, in milliseconds
+const sseRetry = 2000
+
+// JobEvents streams progress of a job as Server-Sent Events
+// @Summary Stream job progress
+// @Description Stream stage changes and byte progress of a job as Server-Sent Events. "progress" events carry a models.Progress, the final "done" event carries the finished models.Job. Reconnecting clients resume after the Last-Event-ID header (or last_event_id query parameter).
+// @Tags Jobs
+// @Produce text/event-stream
+// @Param id path string true "Job ID"
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Param last_event_id query string false "ID of the last event received, for clients that cannot set headers"
+// @Success 200 {string} string "Event stream"
+// @Failure 404 {object} models.ErrorResponse "Job not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/v1/jobs/{id}/events [get]
+func (h *Handler) JobEvents(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	job, err := h.jobManager.Get(ctx, c.Params("id"))
+	if err != nil {
+		return h.jobError(c, err)
+	}
+
+	key := events.JobKey(job.ID)
+	if !h.events.Exists(key) {
+		// Not run by this instance or no longer buffered: report the stored state once
+		name := events.EventProgress
+		if job.Finished() {
+			name = events.EventDone
+		}
+		if err := h.events.Publish(key, name, job); err != nil {
+			return err
+		}
+		h.events.Close(key)
+	}
+
+	return h.streamEvents(c, key)
+}
+
+// ProxyDownloadEvents streams progress of a proxy download as Server-Sent Events
+// @Summary Stream proxy download progress
+// @Description Stream byte progress of a proxy download started with the same progress_id as Server-Sent Events. Subscribing before the download starts is allowed. "progress" and "done" events carry a models.Progress, an "error" event carries a models.ErrorResponse. Reconnecting clients resume after the Last-Event-ID header (or last_event_id query parameter).
+// @Tags Video Processing
+// @Produce text/event-stream
+// @Param progress_id path string true "Progress ID sent with the proxy download request"
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Param last_event_id query string false "ID of the last event received, for clients that cannot set headers"
+// @Success 200 {string} string "Event stream"
+// @Router /api/v1/proxy-download/{progress_id}/events [get]
+func (h *Handler) ProxyDownloadEvents(c *fiber.Ctx) error {
+	return h.streamEvents(c, events.DownloadKey(c.Params("progress_id")))
+}
+
+// streamEvents writes the events of key to the client until the stream ends
+// or the client goes away
+func (h *Handler) streamEvents(c *fiber.Ctx, key string) error {
+	lastEventID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	after, _ := strconv.ParseInt(lastEventID, 10, 64)
+
+	sub := h.events.Subscribe(key, after)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), sseHeartbeat)
+			batch, done := sub.Next(ctx)
+			cancel()
+
+			if len(batch) == 0 && !done {
+				fmt.Fprint(w, ": heartbeat\n\n")
+			}
+			for _, event := range batch {
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Name, event.Data)
+			}
+
+			// A failed flush means the client disconnected
+			if err := w.Flush(); err != nil || done {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// trackDownload wraps a proxied body so bytes copied to the client are
+// published as progress for progressID
+func (h *Handler) trackDownload(progressID string, body io.ReadCloser, size int64) io.ReadCloser {
+	tracked := &downloadEvents{handler: h, progressID: progressID}
+	tracked.ReadCloser = downloader.TrackProgress(body, size, tracked.report)
+	return tracked
+}
+
+// finishDownloadEvents publishes the final event of a proxy download and ends its stream
+func (h *Handler) finishDownloadEvents(progressID string, name string, data interface{}) {
+	if progressID == "" {
+		return
+	}
+
+	key := events.DownloadKey(progressID)
+	if err := h.events.Publish(key, name, data); err != nil {
+		h.logger.WithError(err).WithField("progress_id", progressID).Warn("Failed to publish download event")
+	}
+	h.events.Close(key)
+}
+
+// downloadEvents publishes the progress of a proxied body as it is streamed
+type downloadEvents struct {
+	io.ReadCloser
+	handler    *Handler
+	progressID string
+	last       models.Progress
+}
+
+func (d *downloadEvents) report(progress models.Progress) {
+	d.last = progress
+	if progress.Stage == models.StageDone {
+		return
+	}
+	if err := d.handler.events.Publish(events.DownloadKey(d.progressID), events.EventProgress, progress); err != nil {
+		d.handler.logger.WithError(err).WithField("progress_id", d.progressID).Warn("Failed to publish download event")
+	}
+}
+
+func (d *downloadEvents) Close() error {
+	err := d.ReadCloser.Close()
+
+	if d.last.Stage == models.StageDone {
+		d.handler.finishDownloadEvents(d.progressID, events.EventDone, d.last)
+	} else {
+		d.handler.finishDownloadEvents(d.progressID, events.EventError, models.ErrorResponse{
+			Error: "Download interrupted",
+			Code:  "DOWNLOAD_INTERRUPTED",
+		})
+	}
+	return err
+}
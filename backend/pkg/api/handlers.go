@@ -2,38 +2,55 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 
 	"vidtogallery/internal/models"
+	"vidtogallery/pkg/cache"
 	"vidtogallery/pkg/downloader"
+	"vidtogallery/pkg/events"
+	"vidtogallery/pkg/jobs"
+	"vidtogallery/pkg/quality"
+	"vidtogallery/pkg/webhook"
 )
 
 type Handler struct {
 	downloaderService *downloader.Service
+	cacheService      *cache.Service
+	jobManager        *jobs.Manager
+	events            *events.Broker
+	notifier          *webhook.Notifier
 	logger            *logrus.Logger
 }
 
-func NewHandler(downloaderService *downloader.Service, logger *logrus.Logger) *Handler {
+func NewHandler(downloaderService *downloader.Service, cacheService *cache.Service, jobManager *jobs.Manager, broker *events.Broker, notifier *webhook.Notifier, logger *logrus.Logger) *Handler {
 	return &Handler{
 		downloaderService: downloaderService,
+		cacheService:      cacheService,
+		jobManager:        jobManager,
+		events:            broker,
+		notifier:          notifier,
 		logger:            logger,
 	}
 }
 
 // DownloadVideo downloads a video with specified quality
 // @Summary Download video with quality
-// @Description Download video from social media platform with specified quality
+// @Description Download video from social media platform with specified quality. With quality "auto" the quality is chosen from the Save-Data, ECT, Downlink, Viewport-Width and DPR client hints and the User-Agent, and metadata.quality_reason explains the choice.
 // @Tags Video Processing
 // @Accept json
 // @Produce json
 // @Param request body models.VideoRequest true "Video URL and quality to download"
 // @Success 200 {object} models.VideoResponse "Video downloaded successfully"
-// @Failure 400 {object} models.ErrorResponse "Invalid request"
+// @Failure 400 {object} models.ErrorResponse "Invalid request or quality"
 // @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Failure 503 {object} models.ErrorResponse "All workers are busy and the queue is full; retry after Retry-After seconds"
 // @Router /api/v1/download [post]
 func (h *Handler) DownloadVideo(c *fiber.Ctx) error {
 	var req models.VideoRequest
@@ -52,20 +69,24 @@ func (h *Handler) DownloadVideo(c *fiber.Ctx) error {
 		})
 	}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+	// Create context with the platform's extraction timeout
+	ctx, cancel := context.WithTimeout(c.Context(), h.downloaderService.Timeout(req.URL))
 	defer cancel()
 
 	h.logger.WithField("url", req.URL).Info("Downloading video")
 
-	// Set default quality to "best" if not specified
-	quality := req.Quality
-	if quality == "" {
-		quality = "best"
-	}
+	// Without a quality, the platform's default quality applies
+	qualitySpec, autoReason := h.autoQuality(c, req.Quality)
 
 	// Download video with specified quality
-	response, err := h.downloaderService.ProcessURLWithQuality(ctx, req.URL, quality)
+	response, err := h.downloaderService.ProcessURLWithQuality(ctx, req.URL, qualitySpec)
+	if errors.Is(err, quality.ErrInvalidSpec) {
+		return c.Status(400).JSON(invalidQuality(err))
+	}
+	if errors.Is(err, downloader.ErrQueueFull) {
+		h.logger.WithField("url", req.URL).Warn("Extraction queue full, rejecting request")
+		return c.JSON(h.queueFull(c))
+	}
 	if err != nil {
 		h.logger.WithError(err).WithField("url", req.URL).Error("Failed to download video")
 		return c.Status(500).JSON(models.ErrorResponse{
@@ -75,11 +96,15 @@ func (h *Handler) DownloadVideo(c *fiber.Ctx) error {
 		})
 	}
 
+	if autoReason != "" {
+		response = downloader.WithQualityReason(response, autoReason)
+	}
+
 	h.logger.WithFields(logrus.Fields{
 		"url":       req.URL,
 		"platform":  response.Platform,
 		"video_url": response.VideoURL,
-		"quality":   quality,
+		"quality":   response.Quality,
 	}).Info("Video downloaded successfully")
 
 	return c.JSON(response)
@@ -87,19 +112,45 @@ func (h *Handler) DownloadVideo(c *fiber.Ctx) error {
 
 // HealthCheck returns the health status of the API
 // @Summary Health check endpoint
-// @Description Check if the API is running and healthy
+// @Description Check if the API is running and healthy, with busy workers and queue depth of the extraction and proxy download pools and the yt-dlp version in use
 // @Tags Health
 // @Produce json
-// @Success 200 {object} map[string]interface{} "API is healthy"
+// @Success 200 {object} models.HealthResponse "API is healthy"
 // @Router /health [get]
 func (h *Handler) HealthCheck(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
-		"status":    "ok",
-		"timestamp": time.Now(),
-		"service":   "vidtogallery",
+	return c.JSON(models.HealthResponse{
+		Status:    "ok",
+		Timestamp: time.Now(),
+		Service:   "vidtogallery",
+		Pools:     h.downloaderService.PoolStats(),
+		YtDlp:     h.downloaderService.YtDlpStatus(),
 	})
 }
 
+// invalidQuality is the error body for quality strings that are neither a
+// profile nor a valid spec
+func invalidQuality(err error) models.ErrorResponse {
+	return models.ErrorResponse{
+		Error:   "Invalid quality",
+		Code:    "INVALID_QUALITY",
+		Details: err.Error(),
+	}
+}
+
+// queueFull sets a 503 status with a Retry-After header for requests turned
+// away because every worker is busy and returns the error body to send
+func (h *Handler) queueFull(c *fiber.Ctx) models.ErrorResponse {
+	retryAfter := int(math.Ceil(h.downloaderService.RetryAfter().Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	c.Status(fiber.StatusServiceUnavailable)
+
+	return models.ErrorResponse{
+		Error:   "Server is busy",
+		Code:    "SERVER_BUSY",
+		Details: downloader.ErrQueueFull.Error(),
+	}
+}
+
 // GetQualities returns available video qualities for a given URL
 // @Summary Get available video qualities
 // @Description Get list of available video qualities for a social media URL
@@ -110,6 +161,7 @@ func (h *Handler) HealthCheck(c *fiber.Ctx) error {
 // @Success 200 {object} models.QualitiesResponse "Available qualities retrieved successfully"
 // @Failure 400 {object} models.ErrorResponse "Invalid request"
 // @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Failure 503 {object} models.ErrorResponse "All workers are busy and the queue is full; retry after Retry-After seconds"
 // @Router /api/v1/qualities [post]
 func (h *Handler) GetQualities(c *fiber.Ctx) error {
 	var req models.QualityRequest
@@ -128,14 +180,18 @@ func (h *Handler) GetQualities(c *fiber.Ctx) error {
 		})
 	}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+	// Create context with the platform's extraction timeout
+	ctx, cancel := context.WithTimeout(c.Context(), h.downloaderService.Timeout(req.URL))
 	defer cancel()
 
 	h.logger.WithField("url", req.URL).Info("Getting available qualities")
 
 	// Get available qualities for the video
 	response, err := h.downloaderService.GetAvailableQualities(ctx, req.URL)
+	if errors.Is(err, downloader.ErrQueueFull) {
+		h.logger.WithField("url", req.URL).Warn("Extraction queue full, rejecting request")
+		return c.JSON(h.queueFull(c))
+	}
 	if err != nil {
 		h.logger.WithError(err).WithField("url", req.URL).Error("Failed to get available qualities")
 		return c.Status(500).JSON(models.ErrorResponse{
@@ -154,16 +210,39 @@ func (h *Handler) GetQualities(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
+// GetQualityProfiles lists the named quality profiles
+// @Summary List quality profiles
+// @Description List the server-defined quality profiles. A profile name can be passed wherever a quality selector is accepted.
+// @Tags Video Processing
+// @Produce json
+// @Success 200 {object} models.QualityProfilesResponse "Configured quality profiles"
+// @Router /api/v1/quality-profiles [get]
+func (h *Handler) GetQualityProfiles(c *fiber.Ctx) error {
+	profiles := h.downloaderService.QualityProfiles()
+	response := models.QualityProfilesResponse{Profiles: make([]models.QualityProfile, 0, len(profiles))}
+	for _, profile := range profiles {
+		response.Profiles = append(response.Profiles, models.QualityProfile{
+			Name:        profile.Name,
+			Label:       profile.Label,
+			Description: profile.Description,
+			Quality:     profile.Spec.String(),
+		})
+	}
+	return c.JSON(response)
+}
+
 // ProxyDownload downloads video file through backend to avoid CORS issues
 // @Summary Proxy download video file
 // @Description Download video file through backend proxy to avoid CORS restrictions
 // @Tags Video Processing
 // @Accept json
 // @Produce application/octet-stream
-// @Param request body models.ProxyDownloadRequest true "Video URL to proxy download"
+// @Param request body models.ProxyDownloadRequest true "Video URL to proxy download, optionally with its source post, quality and a progress ID to follow it by"
 // @Success 200 {file} binary "Video file"
+// @Success 304 "Cached file matches If-None-Match"
 // @Failure 400 {object} models.ErrorResponse "Invalid request"
 // @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Failure 503 {object} models.ErrorResponse "All workers are busy and the queue is full; retry after Retry-After seconds"
 // @Router /api/v1/proxy-download [post]
 func (h *Handler) ProxyDownload(c *fiber.Ctx) error {
 	var req models.ProxyDownloadRequest
@@ -182,6 +261,11 @@ func (h *Handler) ProxyDownload(c *fiber.Ctx) error {
 		})
 	}
 
+	req.Quality, _ = h.autoQuality(c, req.Quality)
+	if _, err := h.downloaderService.ResolveQuality(req.SourceURL, req.Quality); err != nil {
+		return c.Status(400).JSON(invalidQuality(err))
+	}
+
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(c.Context(), 60*time.Second)
 	defer cancel()
@@ -189,21 +273,45 @@ func (h *Handler) ProxyDownload(c *fiber.Ctx) error {
 	h.logger.WithField("video_url", req.VideoURL).Info("Proxying video download")
 
 	// Proxy download through downloader service
-	response, err := h.downloaderService.ProxyDownload(ctx, req.VideoURL)
+	response, err := h.downloaderService.ProxyDownload(ctx, &req)
+	if errors.Is(err, downloader.ErrQueueFull) {
+		h.logger.WithField("video_url", req.VideoURL).Warn("Proxy download queue full, rejecting request")
+		errorResponse := h.queueFull(c)
+		h.finishDownloadEvents(req.ProgressID, events.EventError, errorResponse)
+		return c.JSON(errorResponse)
+	}
 	if err != nil {
 		h.logger.WithError(err).WithField("video_url", req.VideoURL).Error("Failed to proxy download video")
-		return c.Status(500).JSON(models.ErrorResponse{
+		errorResponse := models.ErrorResponse{
 			Error:   "Failed to download video",
 			Code:    "PROXY_DOWNLOAD_ERROR",
 			Details: err.Error(),
-		})
+		}
+		h.finishDownloadEvents(req.ProgressID, events.EventError, errorResponse)
+		return c.Status(500).JSON(errorResponse)
+	}
+
+	// Cached files are content-addressed, so their hash is a strong validator
+	if response.ContentHash != "" {
+		etag := fmt.Sprintf("%q", response.ContentHash)
+		c.Set("ETag", etag)
+		if c.Get("If-None-Match") == etag {
+			response.Body.Close()
+			h.finishDownloadEvents(req.ProgressID, events.EventDone, models.Progress{Stage: models.StageDone})
+			return c.SendStatus(fiber.StatusNotModified)
+		}
 	}
 
 	// Set appropriate headers
 	c.Set("Content-Type", "application/octet-stream")
 	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"video_%d.mp4\"", time.Now().Unix()))
 
-	h.logger.WithField("video_url", req.VideoURL).Info("Video proxy download completed")
+	h.logger.WithField("video_url", req.VideoURL).Info("Video proxy download started")
+
+	if req.ProgressID != "" {
+		response.Body = h.trackDownload(req.ProgressID, response.Body, response.Size)
+	}
 
-	return c.Send(response.Data)
+	// The body is streamed after the handler returns and closed by fasthttp when done
+	return c.SendStream(response.Body, int(response.Size))
 }
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"vidtogallery/pkg/quality"
+)
+
+// clientHintHeaders are the request headers the auto quality reads, allowed
+// through CORS so frontends can also send them explicitly
+const clientHintHeaders = "Save-Data,ECT,Downlink,Viewport-Width,DPR,Sec-CH-Viewport-Width,Sec-CH-DPR,Sec-CH-UA-Platform"
+
+// clientHints collects the client hints of a request. The Sec-CH- variants
+// take precedence over the legacy headers.
+func clientHints(c *fiber.Ctx) quality.Hints {
+	header := func(names ...string) string {
+		for _, name := range names {
+			if value := strings.TrimSpace(c.Get(name)); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	hints := quality.Hints{
+		SaveData:  strings.EqualFold(header("Save-Data"), "on"),
+		ECT:       header("ECT"),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Platform:  header("Sec-CH-UA-Platform"),
+	}
+	hints.Downlink, _ = strconv.ParseFloat(header("Downlink"), 64)
+	hints.ViewportWidth, _ = strconv.Atoi(header("Sec-CH-Viewport-Width", "Viewport-Width"))
+	hints.DPR, _ = strconv.ParseFloat(header("Sec-CH-DPR", "DPR"), 64)
+	return hints
+}
+
+// autoQuality replaces the auto quality with the spec chosen from the client
+// hints of c and returns it with the reason for the choice. Other qualities
+// are returned as-is with an empty reason.
+func (h *Handler) autoQuality(c *fiber.Ctx, qualitySpec string) (string, string) {
+	if !strings.EqualFold(strings.TrimSpace(qualitySpec), quality.Auto) {
+		return qualitySpec, ""
+	}
+	spec, reason := h.downloaderService.AutoQuality(clientHints(c))
+	return spec.String(), reason
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"vidtogallery/internal/models"
+	"vidtogallery/pkg/jobs"
+	"vidtogallery/pkg/quality"
+)
+
+// CreateJob starts an asynchronous extraction or download
+// @Summary Create job
+// @Description Start an extraction ("extract") or extraction plus file download ("download") in the background and return its ID immediately. With a callback_url on an allowed host, a signed webhook is POSTed when the job finishes.
+// @Tags Jobs
+// @Accept json
+// @Produce json
+// @Param request body models.JobRequest true "Job type, video URL and quality"
+// @Success 202 {object} models.Job "Job accepted"
+// @Failure 400 {object} models.ErrorResponse "Invalid request"
+// @Failure 503 {object} models.ErrorResponse "Server is shutting down"
+// @Router /api/v1/jobs [post]
+func (h *Handler) CreateJob(c *fiber.Ctx) error {
+	var req models.JobRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to parse request body")
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+			Code:  "INVALID_REQUEST",
+		})
+	}
+
+	if req.URL == "" {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "URL is required",
+			Code:  "MISSING_URL",
+		})
+	}
+
+	req.Quality, req.QualityReason = h.autoQuality(c, req.Quality)
+
+	job, err := h.jobManager.Submit(req)
+	if err != nil {
+		if errors.Is(err, jobs.ErrInvalidJobType) {
+			return c.Status(400).JSON(models.ErrorResponse{
+				Error:   "Invalid job type",
+				Code:    "INVALID_JOB_TYPE",
+				Details: err.Error(),
+			})
+		}
+		if errors.Is(err, quality.ErrInvalidSpec) {
+			return c.Status(400).JSON(invalidQuality(err))
+		}
+		if errors.Is(err, jobs.ErrShuttingDown) {
+			return c.Status(503).JSON(models.ErrorResponse{
+				Error: "Server is shutting down",
+				Code:  "SHUTTING_DOWN",
+			})
+		}
+		return h.callbackError(c, err)
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"job_id":  job.ID,
+		"type":    job.Type,
+		"url":     job.URL,
+		"quality": job.Quality,
+	}).Info("Job created")
+
+	return c.Status(202).JSON(job)
+}
+
+// GetJob returns the state of a job
+// @Summary Get job
+// @Description Get the state, progress and, once finished, the result of a job
+// @Tags Jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.Job "Job state"
+// @Failure 404 {object} models.ErrorResponse "Job not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/v1/jobs/{id} [get]
+func (h *Handler) GetJob(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	job, err := h.jobManager.Get(ctx, c.Params("id"))
+	if err != nil {
+		return h.jobError(c, err)
+	}
+
+	return c.JSON(job)
+}
+
+// CancelJob cancels a queued or running job
+// @Summary Cancel job
+// @Description Cancel a job that has not finished yet
+// @Tags Jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.Job "Cancelled job"
+// @Failure 404 {object} models.ErrorResponse "Job not found"
+// @Failure 409 {object} models.ErrorResponse "Job already finished"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/v1/jobs/{id} [delete]
+func (h *Handler) CancelJob(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	job, err := h.jobManager.Cancel(ctx, c.Params("id"))
+	if err != nil {
+		return h.jobError(c, err)
+	}
+
+	h.logger.WithField("job_id", job.ID).Info("Job cancelled")
+
+	return c.JSON(job)
+}
+
+func (h *Handler) jobError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		return c.Status(404).JSON(models.ErrorResponse{
+			Error: "Job not found",
+			Code:  "JOB_NOT_FOUND",
+		})
+	case errors.Is(err, jobs.ErrJobFinished):
+		return c.Status(409).JSON(models.ErrorResponse{
+			Error: "Job has already finished",
+			Code:  "JOB_FINISHED",
+		})
+	default:
+		h.logger.WithError(err).WithField("job_id", c.Params("id")).Error("Failed to load job")
+		return c.Status(500).JSON(models.ErrorResponse{
+			Error:   "Failed to load job",
+			Code:    "JOB_ERROR",
+			Details: err.Error(),
+		})
+	}
+}
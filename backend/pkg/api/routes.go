@@ -8,21 +8,26 @@ import (
 	"github.com/sirupsen/logrus"
 	fiberSwagger "github.com/swaggo/fiber-swagger"
 
+	"vidtogallery/pkg/cache"
+	"vidtogallery/pkg/config"
 	"vidtogallery/pkg/downloader"
+	"vidtogallery/pkg/events"
+	"vidtogallery/pkg/jobs"
+	"vidtogallery/pkg/webhook"
 )
 
-func SetupRoutes(app *fiber.App, downloaderService *downloader.Service, log *logrus.Logger) {
+func SetupRoutes(app *fiber.App, cfg *config.Config, downloaderService *downloader.Service, cacheService *cache.Service, jobManager *jobs.Manager, broker *events.Broker, notifier *webhook.Notifier, log *logrus.Logger) {
 	// Middleware
 	app.Use(recover.New())
 	app.Use(logger.New())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
-		AllowMethods: "GET,POST,OPTIONS",
-		AllowHeaders: "Origin,Content-Type,Accept",
+		AllowMethods: "GET,POST,DELETE,OPTIONS",
+		AllowHeaders: "Origin,Content-Type,Accept,Authorization,If-None-Match,Last-Event-ID," + clientHintHeaders,
 	}))
 
 	// Initialize handlers
-	handler := NewHandler(downloaderService, log)
+	handler := NewHandler(downloaderService, cacheService, jobManager, broker, notifier, log)
 
 	// Swagger documentation
 	app.Get("/swagger/*", fiberSwagger.WrapHandler)
@@ -33,6 +38,25 @@ func SetupRoutes(app *fiber.App, downloaderService *downloader.Service, log *log
 	// API routes
 	api := app.Group("/api/v1")
 	api.Post("/download", handler.DownloadVideo)
+	api.Post("/batch", handler.BatchDownload)
 	api.Post("/qualities", handler.GetQualities)
+	api.Get("/quality-profiles", handler.GetQualityProfiles)
 	api.Post("/proxy-download", handler.ProxyDownload)
+	api.Get("/proxy-download/:progress_id/events", handler.ProxyDownloadEvents)
+	api.Post("/jobs", handler.CreateJob)
+	api.Get("/jobs/:id", handler.GetJob)
+	api.Delete("/jobs/:id", handler.CancelJob)
+	api.Get("/jobs/:id/events", handler.JobEvents)
+
+	// Admin routes are only mounted when a token is configured
+	if cfg.Admin.Token != "" {
+		admin := api.Group("/admin", AdminAuth(cfg.Admin.Token))
+		admin.Get("/cache/stats", handler.CacheStats)
+		admin.Get("/cache/entry", handler.CacheLookup)
+		admin.Post("/cache/purge", handler.CachePurge)
+		admin.Get("/webhooks/deliveries", handler.WebhookDeliveries)
+		admin.Get("/webhooks/deliveries/:id", handler.WebhookDelivery)
+	} else {
+		log.Info("ADMIN_TOKEN not set, admin API disabled")
+	}
 }
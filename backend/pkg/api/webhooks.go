@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"vidtogallery/internal/models"
+	"vidtogallery/pkg/cache"
+	"vidtogallery/pkg/webhook"
+)
+
+const (
+	// defaultDeliveryLimit is how many deliveries a log request returns by default
+	defaultDeliveryLimit = 50
+	// maxDeliveryLimit caps how many deliveries one log request returns
+	maxDeliveryLimit = 200
+)
+
+// callbackError responds to a callback URL rejected by the webhook notifier
+func (h *Handler) callbackError(c *fiber.Ctx, err error) error {
+	code := "INVALID_CALLBACK_URL"
+	switch {
+	case errors.Is(err, webhook.ErrWebhooksDisabled):
+		code = "CALLBACKS_DISABLED"
+	case errors.Is(err, webhook.ErrHostNotAllowed):
+		code = "CALLBACK_HOST_NOT_ALLOWED"
+	}
+
+	return c.Status(400).JSON(models.ErrorResponse{
+		Error:   "Invalid callback URL",
+		Code:    code,
+		Details: err.Error(),
+	})
+}
+
+// WebhookDeliveries lists recent webhook deliveries
+// @Summary Webhook delivery log
+// @Description List recent webhook deliveries with every attempt, newest first
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Param limit query int false "Maximum number of deliveries (default 50, max 200)"
+// @Success 200 {object} models.WebhookDeliveriesResponse "Recent deliveries"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/v1/admin/webhooks/deliveries [get]
+func (h *Handler) WebhookDeliveries(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", defaultDeliveryLimit)
+	if limit < 1 {
+		limit = defaultDeliveryLimit
+	}
+	if limit > maxDeliveryLimit {
+		limit = maxDeliveryLimit
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	deliveries, err := h.cacheService.RecentWebhookDeliveries(ctx, limit)
+	if err != nil && !errors.Is(err, cache.ErrCacheDisabled) {
+		h.logger.WithError(err).Error("Failed to load webhook deliveries")
+		return c.Status(500).JSON(models.ErrorResponse{
+			Error:   "Failed to load webhook deliveries",
+			Code:    "WEBHOOK_LOG_ERROR",
+			Details: err.Error(),
+		})
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	return c.JSON(models.WebhookDeliveriesResponse{Deliveries: deliveries})
+}
+
+// WebhookDelivery returns one webhook delivery
+// @Summary Webhook delivery
+// @Description Get a webhook delivery with every attempt
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "Delivery ID (sent as the X-VidToGallery-Delivery header)"
+// @Success 200 {object} models.WebhookDelivery "Delivery"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 404 {object} models.ErrorResponse "Delivery not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/v1/admin/webhooks/deliveries/{id} [get]
+func (h *Handler) WebhookDelivery(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	delivery, err := h.cacheService.GetWebhookDelivery(ctx, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, cache.ErrCacheNotFound) || errors.Is(err, cache.ErrCacheDisabled) {
+			return c.Status(404).JSON(models.ErrorResponse{
+				Error: "Delivery not found",
+				Code:  "DELIVERY_NOT_FOUND",
+			})
+		}
+		h.logger.WithError(err).Error("Failed to load webhook delivery")
+		return c.Status(500).JSON(models.ErrorResponse{
+			Error:   "Failed to load webhook delivery",
+			Code:    "WEBHOOK_LOG_ERROR",
+			Details: err.Error(),
+		})
+	}
+
+	return c.JSON(delivery)
+}
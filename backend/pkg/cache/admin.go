@@ -0,0 +1,302 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+
+	"vidtogallery/internal/models"
+)
+
+// Cache key namespaces
+const (
+	NamespaceVideo       = "video"
+	NamespaceVideoFile   = "video_file"
+	NamespaceVideoSource = "video_source"
+	NamespaceVideoInfo   = "video_info"
+)
+
+// scanBatch is the COUNT hint used when iterating keys with SCAN
+const scanBatch = 500
+
+var namespaces = []string{NamespaceVideo, NamespaceVideoFile, NamespaceVideoSource, NamespaceVideoInfo}
+
+var ErrInvalidPrefix = errors.New("prefix must start with a cache namespace")
+
+// counters tracks lookups per namespace since startup
+type counters struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func newCounters() map[string]*counters {
+	stats := make(map[string]*counters, len(namespaces))
+	for _, namespace := range namespaces {
+		stats[namespace] = &counters{}
+	}
+	return stats
+}
+
+func (s *Service) recordLookup(namespace string, hit bool) {
+	if hit {
+		s.counters[namespace].hits.Add(1)
+	} else {
+		s.counters[namespace].misses.Add(1)
+	}
+}
+
+// Stats reports key counts, memory usage and hit ratios per namespace, plus disk usage.
+// Keys are counted with SCAN so large keyspaces never block Redis.
+func (s *Service) Stats(ctx context.Context) (*models.CacheStatsResponse, error) {
+	response := &models.CacheStatsResponse{
+		Enabled:           s.client != nil,
+		Namespaces:        make(map[string]models.CacheNamespaceStats, len(namespaces)),
+		SchemaVersion:     VideoSchemaVersion,
+		VersionMismatches: s.versionMismatches.Load(),
+	}
+
+	for _, namespace := range namespaces {
+		stats := models.CacheNamespaceStats{
+			Hits:   s.counters[namespace].hits.Load(),
+			Misses: s.counters[namespace].misses.Load(),
+		}
+		if total := stats.Hits + stats.Misses; total > 0 {
+			stats.HitRatio = float64(stats.Hits) / float64(total)
+		}
+
+		if s.client != nil {
+			err := s.scan(ctx, escapePattern(namespace+":")+"*", func(keys []string) error {
+				pipe := s.client.Pipeline()
+				usage := make([]*redis.IntCmd, len(keys))
+				for i, key := range keys {
+					usage[i] = pipe.MemoryUsage(ctx, key)
+				}
+				if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+					return err
+				}
+
+				stats.Keys += int64(len(keys))
+				for _, cmd := range usage {
+					stats.Bytes += cmd.Val()
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		response.Namespaces[namespace] = stats
+	}
+
+	if s.files != nil {
+		files, used := s.files.Usage()
+		response.Disk = &models.CacheDiskStats{
+			Files:    files,
+			Bytes:    used,
+			MaxBytes: s.files.maxBytes,
+		}
+	}
+
+	return response, nil
+}
+
+// SourceEntries returns every cached extraction, yt-dlp info and file mapping for a canonical source URL
+func (s *Service) SourceEntries(ctx context.Context, canonicalURL string) ([]models.CacheEntry, error) {
+	if s.client == nil {
+		return nil, ErrCacheDisabled
+	}
+
+	entries := []models.CacheEntry{}
+	for _, pattern := range sourcePatterns(canonicalURL) {
+		err := s.scan(ctx, pattern, func(keys []string) error {
+			pipe := s.client.Pipeline()
+			values := make([]*redis.StringCmd, len(keys))
+			ttls := make([]*redis.DurationCmd, len(keys))
+			for i, key := range keys {
+				values[i] = pipe.Get(ctx, key)
+				ttls[i] = pipe.TTL(ctx, key)
+			}
+			if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+				return err
+			}
+
+			for i, key := range keys {
+				data, err := values[i].Bytes()
+				if err != nil {
+					// Expired between SCAN and GET
+					continue
+				}
+				entry := models.CacheEntry{
+					Key:        key,
+					TTLSeconds: int64(ttls[i].Val().Seconds()),
+				}
+				switch {
+				case strings.HasPrefix(key, NamespaceVideoSource+":"):
+					entry.File = &models.VideoFile{}
+					if err := json.Unmarshal(data, entry.File); err != nil {
+						s.logger.WithError(err).WithField("key", key).Warn("Failed to decode cache entry")
+						entry.File = nil
+					}
+				case strings.HasPrefix(key, NamespaceVideoInfo+":"):
+					// The info itself is large and opaque, so only its header is listed
+					header, _, _ := decodeEnvelope(data, InfoSchemaVersion)
+					entry.SchemaVersion = int(header.Schema)
+					entry.Extractor = header.Extractor
+					if !header.CreatedAt.IsZero() {
+						entry.CreatedAt = &header.CreatedAt
+					}
+				default:
+					// Undecodable entries are still listed so they can be inspected and purged
+					video, header, _ := s.decodeVideo(key, data)
+					entry.Video = video
+					entry.SchemaVersion = int(header.Schema)
+					entry.Extractor = header.Extractor
+					if !header.CreatedAt.IsZero() {
+						entry.CreatedAt = &header.CreatedAt
+					}
+				}
+				entries = append(entries, entry)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// PurgeSource deletes every cached extraction, yt-dlp info and file mapping for a canonical source URL
+func (s *Service) PurgeSource(ctx context.Context, canonicalURL string) (int64, error) {
+	var deleted int64
+	for _, pattern := range sourcePatterns(canonicalURL) {
+		n, err := s.purge(ctx, pattern, nil)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// PurgeVideoFile deletes the file index entry for a CDN video URL
+func (s *Service) PurgeVideoFile(ctx context.Context, videoURL string) (int64, error) {
+	if s.client == nil {
+		return 0, ErrCacheDisabled
+	}
+	return s.client.Unlink(ctx, s.videoFileKey(videoURL)).Result()
+}
+
+// PurgePrefix deletes every key starting with prefix, which must name a cache namespace
+func (s *Service) PurgePrefix(ctx context.Context, prefix string) (int64, error) {
+	valid := false
+	for _, namespace := range namespaces {
+		if strings.HasPrefix(prefix, namespace+":") {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return 0, ErrInvalidPrefix
+	}
+
+	return s.purge(ctx, escapePattern(prefix)+"*", nil)
+}
+
+// PurgeMatching deletes keys in every namespace for which match returns true.
+// match receives the namespace and the key with its namespace prefix removed.
+func (s *Service) PurgeMatching(ctx context.Context, match func(namespace string, id string) bool) (int64, error) {
+	var deleted int64
+	for _, namespace := range namespaces {
+		prefix := namespace + ":"
+		n, err := s.purge(ctx, escapePattern(prefix)+"*", func(key string) bool {
+			return match(namespace, strings.TrimPrefix(key, prefix))
+		})
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// purge unlinks keys matching pattern, optionally filtered by match
+func (s *Service) purge(ctx context.Context, pattern string, match func(key string) bool) (int64, error) {
+	if s.client == nil {
+		return 0, ErrCacheDisabled
+	}
+
+	var deleted int64
+	err := s.scan(ctx, pattern, func(keys []string) error {
+		if match != nil {
+			selected := keys[:0]
+			for _, key := range keys {
+				if match(key) {
+					selected = append(selected, key)
+				}
+			}
+			keys = selected
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		n, err := s.client.Unlink(ctx, keys...).Result()
+		deleted += n
+		return err
+	})
+
+	if deleted > 0 {
+		s.logger.WithField("pattern", pattern).WithField("deleted", deleted).Info("Purged cache entries")
+	}
+	return deleted, err
+}
+
+// scan iterates keys matching pattern in batches without blocking Redis
+func (s *Service) scan(ctx context.Context, pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, pattern, scanBatch).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// sourcePatterns returns the SCAN patterns for all qualities of a canonical source URL
+func sourcePatterns(canonicalURL string) []string {
+	escaped := escapePattern(canonicalURL + ":")
+	return []string{
+		NamespaceVideo + ":" + escaped + "*",
+		NamespaceVideoSource + ":" + escaped + "*",
+		NamespaceVideoInfo + ":" + escapePattern(canonicalURL),
+	}
+}
+
+// escapePattern escapes glob metacharacters so s matches literally in SCAN MATCH
+func escapePattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
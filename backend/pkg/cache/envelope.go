@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// VideoSchemaVersion is the schema version of models.VideoResponse as stored in
+// the cache. Bump it whenever a field is added, removed or changes meaning so
+// entries written by older releases are ignored instead of half-decoded.
+const VideoSchemaVersion = 1
+
+// Envelope layout (big endian):
+//
+//	magic     2 bytes  "VG"
+//	schema    2 bytes  payload schema version
+//	codec     1 byte   payload compression
+//	created   8 bytes  creation time, unix milliseconds
+//	extractor 1 byte length followed by the extractor name
+//	payload   remaining bytes
+var envelopeMagic = []byte("VG")
+
+const envelopeHeaderSize = 2 + 2 + 1 + 8 + 1
+
+// Codec identifies how an envelope payload is compressed
+type Codec byte
+
+const (
+	CodecNone Codec = iota
+	CodecGzip
+	CodecZstd
+)
+
+var (
+	ErrEnvelopeVersion = errors.New("cache entry schema version mismatch")
+	ErrEnvelopeCorrupt = errors.New("cache entry is corrupt")
+)
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// ParseCodec maps a configuration value to a codec
+func ParseCodec(name string) (Codec, error) {
+	switch name {
+	case "none", "":
+		return CodecNone, nil
+	case "gzip":
+		return CodecGzip, nil
+	case "zstd":
+		return CodecZstd, nil
+	default:
+		return CodecNone, fmt.Errorf("unknown cache compression %q", name)
+	}
+}
+
+// envelope is the header stored in front of every cached payload
+type envelope struct {
+	Schema    uint16
+	Codec     Codec
+	CreatedAt time.Time
+	Extractor string
+}
+
+// encodeEnvelope wraps payload in an envelope, compressing it with codec
+func encodeEnvelope(header envelope, payload []byte) ([]byte, error) {
+	compressed, err := compress(header.Codec, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	extractor := header.Extractor
+	if len(extractor) > 255 {
+		extractor = extractor[:255]
+	}
+
+	buf := make([]byte, 0, envelopeHeaderSize+len(extractor)+len(compressed))
+	buf = append(buf, envelopeMagic...)
+	buf = binary.BigEndian.AppendUint16(buf, header.Schema)
+	buf = append(buf, byte(header.Codec))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(header.CreatedAt.UnixMilli()))
+	buf = append(buf, byte(len(extractor)))
+	buf = append(buf, extractor...)
+	buf = append(buf, compressed...)
+	return buf, nil
+}
+
+// decodeEnvelope unwraps data written by encodeEnvelope and returns the
+// decompressed payload. Entries from before envelopes existed are reported
+// as schema version 0 together with ErrEnvelopeVersion.
+func decodeEnvelope(data []byte, schema uint16) (envelope, []byte, error) {
+	if !bytes.HasPrefix(data, envelopeMagic) {
+		return envelope{}, nil, ErrEnvelopeVersion
+	}
+	if len(data) < envelopeHeaderSize {
+		return envelope{}, nil, ErrEnvelopeCorrupt
+	}
+
+	header := envelope{
+		Schema:    binary.BigEndian.Uint16(data[2:4]),
+		Codec:     Codec(data[4]),
+		CreatedAt: time.UnixMilli(int64(binary.BigEndian.Uint64(data[5:13]))),
+	}
+	extractorLen := int(data[13])
+	if len(data) < envelopeHeaderSize+extractorLen {
+		return header, nil, ErrEnvelopeCorrupt
+	}
+	header.Extractor = string(data[envelopeHeaderSize : envelopeHeaderSize+extractorLen])
+
+	if header.Schema != schema {
+		return header, nil, ErrEnvelopeVersion
+	}
+
+	payload, err := decompress(header.Codec, data[envelopeHeaderSize+extractorLen:])
+	if err != nil {
+		return header, nil, fmt.Errorf("%w: %v", ErrEnvelopeCorrupt, err)
+	}
+	return header, payload, nil
+}
+
+func compress(codec Codec, payload []byte) ([]byte, error) {
+	switch codec {
+	case CodecNone:
+		return payload, nil
+	case CodecGzip:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CodecZstd:
+		return zstdEncoder.EncodeAll(payload, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown codec %d", codec)
+	}
+}
+
+func decompress(codec Codec, data []byte) ([]byte, error) {
+	switch codec {
+	case CodecNone:
+		return data, nil
+	case CodecGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	case CodecZstd:
+		return zstdDecoder.DecodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("unknown codec %d", codec)
+	}
+}
@@ -0,0 +1,271 @@
+package cache
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// tempSuffix marks files that are still being written; they are discarded on startup
+const tempSuffix = ".tmp"
+
+// FileStore keeps downloaded media on disk under a total size budget, evicting
+// the least recently used files first. Files are written to a temporary name and
+// renamed into place so a crash never leaves a partial file under a final name.
+type FileStore struct {
+	dir      string
+	maxBytes int64
+	logger   *logrus.Logger
+
+	mu    sync.Mutex
+	used  int64
+	lru   *list.List
+	files map[string]*list.Element
+}
+
+type fileEntry struct {
+	name string
+	size int64
+}
+
+// NewFileStore opens the store in dir, removing leftovers from interrupted
+// writes and rebuilding the LRU order from file modification times
+func NewFileStore(dir string, maxBytes int64, logger *logrus.Logger) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	store := &FileStore{
+		dir:      dir,
+		maxBytes: maxBytes,
+		logger:   logger,
+		lru:      list.New(),
+		files:    make(map[string]*list.Element),
+	}
+
+	if err := store.recover(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (f *FileStore) recover() error {
+	dirEntries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return err
+	}
+
+	type recovered struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	var found []recovered
+
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			continue
+		}
+		name := dirEntry.Name()
+		if strings.HasSuffix(name, tempSuffix) {
+			os.Remove(filepath.Join(f.dir, name))
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		found = append(found, recovered{name: name, size: info.Size(), modTime: info.ModTime()})
+	}
+
+	// Most recently used first, matching the front of the list
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].modTime.After(found[j].modTime)
+	})
+	for _, file := range found {
+		f.files[file.name] = f.lru.PushBack(&fileEntry{name: file.name, size: file.size})
+		f.used += file.size
+	}
+
+	f.mu.Lock()
+	f.evict("")
+	f.mu.Unlock()
+
+	f.logger.WithFields(logrus.Fields{
+		"dir":   f.dir,
+		"files": len(f.files),
+		"bytes": f.used,
+	}).Info("File cache recovered")
+	return nil
+}
+
+// Put copies r into the store under name
+func (f *FileStore) Put(name string, r io.Reader) (int64, error) {
+	tmp, err := os.CreateTemp(f.dir, name+"-*"+tempSuffix)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), f.path(name)); err != nil {
+		return 0, err
+	}
+
+	f.add(name, size)
+	return size, nil
+}
+
+// PutFile stores a copy of the file at path under name, hard-linking it when
+// the file lives on the same filesystem as the store. Names are content hashes,
+// so a file already stored under name is kept and only marked as recently used.
+func (f *FileStore) PutFile(name string, path string) (int64, error) {
+	if size, ok := f.touch(name); ok {
+		return size, nil
+	}
+
+	tmpPath := filepath.Join(f.dir, fmt.Sprintf("%s-%d%s", name, time.Now().UnixNano(), tempSuffix))
+
+	if err := os.Link(path, tmpPath); err == nil {
+		defer os.Remove(tmpPath)
+
+		info, err := os.Stat(tmpPath)
+		if err != nil {
+			return 0, err
+		}
+		if err := os.Rename(tmpPath, f.path(name)); err != nil {
+			return 0, err
+		}
+		f.add(name, info.Size())
+		return info.Size(), nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+	return f.Put(name, src)
+}
+
+// Open returns the stored file and its size, marking it as recently used
+func (f *FileStore) Open(name string) (*os.File, int64, error) {
+	size, ok := f.touch(name)
+	if !ok {
+		return nil, 0, ErrCacheNotFound
+	}
+
+	file, err := os.Open(f.path(name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			f.Remove(name)
+			return nil, 0, ErrCacheNotFound
+		}
+		return nil, 0, err
+	}
+
+	return file, size, nil
+}
+
+// Remove deletes name from the store
+func (f *FileStore) Remove(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if element, ok := f.files[name]; ok {
+		f.drop(element)
+	}
+}
+
+// Usage returns the number of stored files and their total size in bytes
+func (f *FileStore) Usage() (int, int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.files), f.used
+}
+
+// touch marks name as recently used and returns its size if it is stored
+func (f *FileStore) touch(name string) (int64, bool) {
+	f.mu.Lock()
+	element, ok := f.files[name]
+	var size int64
+	if ok {
+		f.lru.MoveToFront(element)
+		size = element.Value.(*fileEntry).size
+	}
+	f.mu.Unlock()
+
+	// Persist recency so the LRU order survives a restart
+	if ok {
+		now := time.Now()
+		os.Chtimes(f.path(name), now, now)
+	}
+	return size, ok
+}
+
+func (f *FileStore) add(name string, size int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if element, ok := f.files[name]; ok {
+		entry := element.Value.(*fileEntry)
+		f.used += size - entry.size
+		entry.size = size
+		f.lru.MoveToFront(element)
+	} else {
+		f.files[name] = f.lru.PushFront(&fileEntry{name: name, size: size})
+		f.used += size
+	}
+
+	f.evict(name)
+}
+
+// evict removes least recently used files until the store fits its budget,
+// never evicting keep. The caller must hold f.mu.
+func (f *FileStore) evict(keep string) {
+	for f.used > f.maxBytes {
+		element := f.lru.Back()
+		if element == nil || element.Value.(*fileEntry).name == keep {
+			return
+		}
+
+		f.logger.WithField("file", element.Value.(*fileEntry).name).Debug("Evicting cached file")
+		f.drop(element)
+	}
+}
+
+// drop removes an entry and its file. The caller must hold f.mu.
+func (f *FileStore) drop(element *list.Element) {
+	entry := element.Value.(*fileEntry)
+	f.lru.Remove(element)
+	delete(f.files, entry.name)
+	f.used -= entry.size
+
+	// Readers that already opened the file keep their handle until they close it
+	if err := os.Remove(f.path(entry.name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		f.logger.WithError(err).WithField("file", entry.name).Warn("Failed to remove cached file")
+	}
+}
+
+func (f *FileStore) path(name string) string {
+	return filepath.Join(f.dir, name)
+}
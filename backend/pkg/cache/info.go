@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// InfoSchemaVersion is the schema version of the yt-dlp info JSON stored in
+// the video_info namespace. Bump it whenever the downloader starts relying
+// on a field older entries may lack.
+const InfoSchemaVersion = 1
+
+// GetInfo returns the raw yt-dlp info JSON cached for a canonical source URL
+func (s *Service) GetInfo(ctx context.Context, canonicalURL string) ([]byte, bool) {
+	if s.client == nil {
+		return nil, false
+	}
+
+	key := s.infoKey(canonicalURL)
+	data, err := s.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			s.logger.WithError(err).WithField("key", key).Error("Failed to get from cache")
+		}
+		s.recordLookup(NamespaceVideoInfo, false)
+		return nil, false
+	}
+
+	_, payload, err := decodeEnvelope(data, InfoSchemaVersion)
+	if err != nil {
+		if !errors.Is(err, ErrEnvelopeVersion) {
+			s.logger.WithError(err).WithField("key", key).Error("Failed to decode cached info")
+		}
+		s.recordLookup(NamespaceVideoInfo, false)
+		return nil, false
+	}
+
+	s.recordLookup(NamespaceVideoInfo, true)
+	return payload, true
+}
+
+// SetInfo caches the raw yt-dlp info JSON of a canonical source URL. The
+// caller picks the TTL since the info holds signed URLs that expire.
+func (s *Service) SetInfo(ctx context.Context, canonicalURL string, info []byte, extractor string, ttl time.Duration) error {
+	if s.client == nil || ttl <= 0 {
+		return nil
+	}
+
+	key := s.infoKey(canonicalURL)
+	data, err := encodeEnvelope(envelope{
+		Schema:    InfoSchemaVersion,
+		Codec:     s.codec,
+		CreatedAt: time.Now(),
+		Extractor: extractor,
+	}, info)
+	if err != nil {
+		return err
+	}
+
+	if err := s.client.Set(ctx, key, data, ttl).Err(); err != nil {
+		s.logger.WithError(err).WithField("key", key).Error("Failed to set cache")
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"url": canonicalURL,
+		"ttl": ttl,
+		"key": key,
+	}).Debug("Video info cached successfully")
+
+	return nil
+}
+
+// infoKey generates a cache key for the yt-dlp info of a source post
+func (s *Service) infoKey(canonicalURL string) string {
+	return NamespaceVideoInfo + ":" + canonicalURL
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+
+	"vidtogallery/internal/models"
+)
+
+// queuedJobsKey is a set of IDs of jobs that have not started yet
+const queuedJobsKey = "jobs:queued"
+
+// SetJob persists job state so it survives client reconnects and restarts
+func (s *Service) SetJob(ctx context.Context, job *models.Job) error {
+	if s.client == nil {
+		return ErrCacheDisabled
+	}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, s.jobKey(job.ID), data, s.jobTTL)
+	if job.State == models.JobStateQueued {
+		pipe.SAdd(ctx, queuedJobsKey, job.ID)
+	} else {
+		pipe.SRem(ctx, queuedJobsKey, job.ID)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// GetJob loads a persisted job
+func (s *Service) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	if s.client == nil {
+		return nil, ErrCacheDisabled
+	}
+
+	data, err := s.client.Get(ctx, s.jobKey(id)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrCacheNotFound
+		}
+		return nil, err
+	}
+
+	var job models.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// QueuedJobs returns the IDs of persisted jobs that have not started yet
+func (s *Service) QueuedJobs(ctx context.Context) ([]string, error) {
+	if s.client == nil {
+		return nil, ErrCacheDisabled
+	}
+	return s.client.SMembers(ctx, queuedJobsKey).Result()
+}
+
+// ClaimQueuedJob removes id from the queued set and reports whether this
+// caller was the one to remove it
+func (s *Service) ClaimQueuedJob(ctx context.Context, id string) (bool, error) {
+	if s.client == nil {
+		return false, ErrCacheDisabled
+	}
+
+	removed, err := s.client.SRem(ctx, queuedJobsKey, id).Result()
+	if err != nil {
+		return false, err
+	}
+	return removed == 1, nil
+}
+
+func (s *Service) jobKey(id string) string {
+	return "job:" + id
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -22,9 +24,23 @@ type Service struct {
 	client   *redis.Client
 	logger   *logrus.Logger
 	videoTTL time.Duration
+	fileTTL  time.Duration
+	jobTTL   time.Duration
+	files    *FileStore
+	counters map[string]*counters
+	codec    Codec
+
+	// versionMismatches counts entries ignored because of an unknown schema version
+	versionMismatches atomic.Int64
 }
 
 func NewService(cfg *config.Config, logger *logrus.Logger) *Service {
+	codec, err := ParseCodec(cfg.Cache.Compression)
+	if err != nil {
+		logger.WithError(err).Warn("Invalid cache compression, falling back to zstd")
+		codec = CodecZstd
+	}
+
 	opts, err := redis.ParseURL(cfg.Redis.URL)
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to parse Redis URL")
@@ -47,14 +63,30 @@ func NewService(cfg *config.Config, logger *logrus.Logger) *Service {
 			client:   nil,
 			logger:   logger,
 			videoTTL: cfg.Cache.VideoTTL,
+			fileTTL:  cfg.Cache.FileTTL,
+			jobTTL:   cfg.Jobs.TTL,
+			counters: newCounters(),
+			codec:    codec,
 		}
 	}
 
 	logger.Info("Redis connection established")
+
+	// Redis holds the index, so the file cache is only useful when Redis is available
+	files, err := NewFileStore(cfg.Cache.FileDir, cfg.Cache.FileMaxSize, logger)
+	if err != nil {
+		logger.WithError(err).WithField("dir", cfg.Cache.FileDir).Warn("File cache unavailable, video files will not be cached")
+	}
+
 	return &Service{
 		client:   client,
 		logger:   logger,
 		videoTTL: cfg.Cache.VideoTTL,
+		fileTTL:  cfg.Cache.FileTTL,
+		jobTTL:   cfg.Jobs.TTL,
+		files:    files,
+		counters: newCounters(),
+		codec:    codec,
 	}
 }
 
@@ -64,22 +96,24 @@ func (s *Service) GetVideo(ctx context.Context, url string) (*models.VideoRespon
 	}
 
 	key := s.videoKey(url)
-	data, err := s.client.Get(ctx, key).Result()
+	data, err := s.client.Get(ctx, key).Bytes()
 	if err != nil {
 		if err != redis.Nil {
 			s.logger.WithError(err).WithField("key", key).Error("Failed to get from cache")
 		}
+		s.recordLookup(NamespaceVideo, false)
 		return nil, false
 	}
 
-	var video models.VideoResponse
-	if err := json.Unmarshal([]byte(data), &video); err != nil {
-		s.logger.WithError(err).WithField("key", key).Error("Failed to unmarshal cached video")
+	video, _, err := s.decodeVideo(key, data)
+	if err != nil {
+		s.recordLookup(NamespaceVideo, false)
 		return nil, false
 	}
 
+	s.recordLookup(NamespaceVideo, true)
 	s.logger.WithField("url", url).Debug("Video found in cache")
-	return &video, true
+	return video, true
 }
 
 func (s *Service) SetVideo(ctx context.Context, url string, video *models.VideoResponse) error {
@@ -88,7 +122,17 @@ func (s *Service) SetVideo(ctx context.Context, url string, video *models.VideoR
 	}
 
 	key := s.videoKey(url)
-	data, err := json.Marshal(video)
+	payload, err := json.Marshal(video)
+	if err != nil {
+		return err
+	}
+
+	data, err := encodeEnvelope(envelope{
+		Schema:    VideoSchemaVersion,
+		Codec:     s.codec,
+		CreatedAt: time.Now(),
+		Extractor: video.Metadata["extractor"],
+	}, payload)
 	if err != nil {
 		return err
 	}
@@ -107,41 +151,134 @@ func (s *Service) SetVideo(ctx context.Context, url string, video *models.VideoR
 	return nil
 }
 
-// GetVideoFile retrieves a cached video file
-func (s *Service) GetVideoFile(ctx context.Context, videoURL string) ([]byte, error) {
-	if s.client == nil {
-		return nil, ErrCacheDisabled
+// decodeVideo unwraps a cached video entry. Entries written with another
+// schema version are ignored and counted rather than partially decoded.
+func (s *Service) decodeVideo(key string, data []byte) (*models.VideoResponse, envelope, error) {
+	header, payload, err := decodeEnvelope(data, VideoSchemaVersion)
+	if err != nil {
+		if errors.Is(err, ErrEnvelopeVersion) {
+			s.versionMismatches.Add(1)
+			s.logger.WithFields(logrus.Fields{
+				"key":      key,
+				"schema":   header.Schema,
+				"expected": VideoSchemaVersion,
+			}).Debug("Ignoring cached video with different schema version")
+		} else {
+			s.logger.WithError(err).WithField("key", key).Error("Failed to decode cached video")
+		}
+		return nil, header, err
+	}
+
+	var video models.VideoResponse
+	if err := json.Unmarshal(payload, &video); err != nil {
+		s.logger.WithError(err).WithField("key", key).Error("Failed to unmarshal cached video")
+		return nil, header, err
+	}
+
+	return &video, header, nil
+}
+
+// GetVideoFile opens the cached file downloaded from videoURL
+func (s *Service) GetVideoFile(ctx context.Context, videoURL string) (*os.File, *models.VideoFile, error) {
+	return s.openIndexedFile(ctx, NamespaceVideoFile, s.videoFileKey(videoURL))
+}
+
+// GetSourceFile opens the cached file for a source post and quality, no matter
+// which (possibly since rotated) CDN URL it was downloaded from
+func (s *Service) GetSourceFile(ctx context.Context, sourceKey string) (*os.File, *models.VideoFile, error) {
+	return s.openIndexedFile(ctx, NamespaceVideoSource, s.videoSourceKey(sourceKey))
+}
+
+func (s *Service) openIndexedFile(ctx context.Context, namespace string, key string) (*os.File, *models.VideoFile, error) {
+	if s.client == nil || s.files == nil {
+		return nil, nil, ErrCacheDisabled
 	}
 
-	key := s.videoFileKey(videoURL)
 	data, err := s.client.Get(ctx, key).Bytes()
 	if err != nil {
+		s.recordLookup(namespace, false)
 		if err == redis.Nil {
-			return nil, ErrCacheNotFound
+			return nil, nil, ErrCacheNotFound
+		}
+		return nil, nil, err
+	}
+
+	var entry models.VideoFile
+	if err := json.Unmarshal(data, &entry); err != nil {
+		s.logger.WithError(err).WithField("key", key).Error("Failed to unmarshal video file index entry")
+		s.recordLookup(namespace, false)
+		return nil, nil, ErrCacheNotFound
+	}
+
+	file, _, err := s.files.Open(entry.Hash)
+	if err != nil {
+		if errors.Is(err, ErrCacheNotFound) {
+			// Evicted from disk; drop the dangling index entry
+			s.client.Del(ctx, key)
 		}
-		return nil, err
+		s.recordLookup(namespace, false)
+		return nil, nil, err
 	}
 
-	return data, nil
+	s.recordLookup(namespace, true)
+	return file, &entry, nil
 }
 
-// CacheVideoFile stores a video file in cache with custom TTL
-func (s *Service) CacheVideoFile(ctx context.Context, videoURL string, data []byte, ttl time.Duration) error {
-	if s.client == nil {
+// CacheVideoFile stores the downloaded file at path under its content hash and
+// indexes it in Redis by video URL and, when sourceKey is set, by source post
+// and quality. Identical bytes fetched from different URLs are stored once.
+func (s *Service) CacheVideoFile(ctx context.Context, videoURL string, sourceKey string, path string, hash string) error {
+	if s.client == nil || s.files == nil {
 		return ErrCacheDisabled
 	}
 
-	key := s.videoFileKey(videoURL)
-	return s.client.Set(ctx, key, data, ttl).Err()
+	size, err := s.files.PutFile(hash, path)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(models.VideoFile{
+		Hash:      hash,
+		Size:      size,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Other index entries may share the file, so it is left for LRU eviction on failure
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, s.videoFileKey(videoURL), data, s.fileTTL)
+	if sourceKey != "" {
+		pipe.Set(ctx, s.videoSourceKey(sourceKey), data, s.fileTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"video_url": videoURL,
+		"source":    sourceKey,
+		"hash":      hash,
+		"size":      size,
+		"ttl":       s.fileTTL,
+	}).Debug("Video file cached successfully")
+
+	return nil
 }
 
 func (s *Service) videoKey(url string) string {
-	return "video:" + url
+	return NamespaceVideo + ":" + url
 }
 
 // videoFileKey generates a cache key for video files
 func (s *Service) videoFileKey(videoURL string) string {
-	return "video_file:" + videoURL
+	return NamespaceVideoFile + ":" + videoURL
+}
+
+// videoSourceKey generates a cache key mapping a source post and quality to a video file
+func (s *Service) videoSourceKey(sourceKey string) string {
+	return NamespaceVideoSource + ":" + sourceKey
 }
 
 func (s *Service) Close() error {
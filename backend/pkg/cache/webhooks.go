@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+
+	"vidtogallery/internal/models"
+)
+
+// webhookDeliveriesKey lists delivery IDs, newest first
+const webhookDeliveriesKey = "webhook_deliveries"
+
+// maxWebhookDeliveries caps the length of the delivery log
+const maxWebhookDeliveries = 1000
+
+// AddWebhookDelivery stores a new delivery and adds it to the delivery log
+func (s *Service) AddWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if s.client == nil {
+		return ErrCacheDisabled
+	}
+
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, s.webhookDeliveryKey(delivery.ID), data, s.jobTTL)
+	pipe.LPush(ctx, webhookDeliveriesKey, delivery.ID)
+	pipe.LTrim(ctx, webhookDeliveriesKey, 0, maxWebhookDeliveries-1)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// UpdateWebhookDelivery stores the latest attempts of a delivery
+func (s *Service) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if s.client == nil {
+		return ErrCacheDisabled
+	}
+
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.webhookDeliveryKey(delivery.ID), data, s.jobTTL).Err()
+}
+
+// GetWebhookDelivery loads one delivery
+func (s *Service) GetWebhookDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	if s.client == nil {
+		return nil, ErrCacheDisabled
+	}
+
+	data, err := s.client.Get(ctx, s.webhookDeliveryKey(id)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrCacheNotFound
+		}
+		return nil, err
+	}
+
+	var delivery models.WebhookDelivery
+	if err := json.Unmarshal(data, &delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// RecentWebhookDeliveries returns up to limit deliveries, newest first.
+// Deliveries that have expired are skipped.
+func (s *Service) RecentWebhookDeliveries(ctx context.Context, limit int) ([]models.WebhookDelivery, error) {
+	if s.client == nil {
+		return nil, ErrCacheDisabled
+	}
+
+	ids, err := s.client.LRange(ctx, webhookDeliveriesKey, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	if len(ids) == 0 {
+		return deliveries, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.webhookDeliveryKey(id)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var delivery models.WebhookDelivery
+		if err := json.Unmarshal([]byte(data), &delivery); err != nil {
+			s.logger.WithError(err).WithField("key", keys[i]).Warn("Failed to decode webhook delivery")
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (s *Service) webhookDeliveryKey(id string) string {
+	return "webhook_delivery:" + id
+}
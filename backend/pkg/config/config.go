@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -10,8 +12,9 @@ import (
 
 type Config struct {
 	Server struct {
-		Port string
-		Host string
+		Port            string
+		Host            string
+		ShutdownTimeout time.Duration
 	}
 	Redis struct {
 		URL      string
@@ -19,12 +22,74 @@ type Config struct {
 		DB       int
 	}
 	Cache struct {
-		TTL      time.Duration
-		VideoTTL time.Duration
+		TTL         time.Duration
+		VideoTTL    time.Duration
+		FreshTTL    time.Duration
+		MaxStale    time.Duration
+		InfoTTL     time.Duration
+		FileTTL     time.Duration
+		FileDir     string
+		FileMaxSize int64
+		Compression string
 	}
 	Download struct {
-		MaxConcurrent int
-		Timeout       time.Duration
+		MaxConcurrent      int
+		MaxConcurrentProxy int
+		ExtractQueueSize   int
+		ProxyQueueSize     int
+		RetryAfter         time.Duration
+		Timeout            time.Duration
+		ProxyTimeout       time.Duration
+		SpoolDir           string
+	}
+	Platforms struct {
+		MaxConcurrent map[string]int
+		MinInterval   map[string]time.Duration
+		MaxSlowdown   time.Duration
+		// Timeout overrides Download.Timeout for extractions per platform
+		Timeout map[string]time.Duration
+		// DefaultQuality is the quality profile or selector used when a
+		// request for the platform does not name one
+		DefaultQuality map[string]string
+		// YtDlpArgs and ExtractorArgs are added to every yt-dlp run for the
+		// platform, the latter as --extractor-args values
+		YtDlpArgs     map[string][]string
+		ExtractorArgs map[string][]string
+	}
+	Quality struct {
+		Profiles map[string]string
+	}
+	YtDlp struct {
+		Path          string
+		BinDir        string
+		MinVersion    string
+		CheckInterval time.Duration
+	}
+	Workers struct {
+		Size           int
+		Python         string
+		MaxJobs        int
+		HealthInterval time.Duration
+	}
+	Retry struct {
+		MaxAttempts int
+		BaseDelay   time.Duration
+		MaxDelay    time.Duration
+		Proxies     []string
+	}
+	Jobs struct {
+		TTL            time.Duration
+		EventRetention time.Duration
+	}
+	Webhooks struct {
+		Secret       string
+		AllowedHosts []string
+		MaxAttempts  int
+		Backoff      time.Duration
+		Timeout      time.Duration
+	}
+	Admin struct {
+		Token string
 	}
 	UserAgent struct {
 		RotateAgents bool
@@ -42,6 +107,7 @@ func Load() (*Config, error) {
 
 	cfg.Server.Port = getEnv("PORT", "8080")
 	cfg.Server.Host = getEnv("HOST", "0.0.0.0")
+	cfg.Server.ShutdownTimeout = getEnvAsDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
 
 	cfg.Redis.URL = getEnv("REDIS_URL", "redis://localhost:6379")
 	cfg.Redis.Password = getEnv("REDIS_PASSWORD", "")
@@ -49,15 +115,69 @@ func Load() (*Config, error) {
 
 	cfg.Cache.TTL = getEnvAsDuration("CACHE_TTL", 24*time.Hour)
 	cfg.Cache.VideoTTL = getEnvAsDuration("VIDEO_CACHE_TTL", 24*time.Hour)
+	cfg.Cache.FreshTTL = getEnvAsDuration("VIDEO_CACHE_FRESH_TTL", 15*time.Minute)
+	cfg.Cache.MaxStale = getEnvAsDuration("VIDEO_CACHE_MAX_STALE", 6*time.Hour)
+	cfg.Cache.InfoTTL = getEnvAsDuration("VIDEO_INFO_CACHE_TTL", 10*time.Minute)
+	cfg.Cache.FileTTL = getEnvAsDuration("VIDEO_FILE_CACHE_TTL", time.Hour)
+	cfg.Cache.FileDir = getEnv("VIDEO_FILE_CACHE_DIR", filepath.Join(os.TempDir(), "vidtogallery-cache"))
+	cfg.Cache.FileMaxSize = getEnvAsBytes("VIDEO_FILE_CACHE_MAX_SIZE", 1<<30)
+	cfg.Cache.Compression = getEnv("CACHE_COMPRESSION", "zstd")
 
 	cfg.Download.MaxConcurrent = getEnvAsInt("MAX_CONCURRENT_DOWNLOADS", 5)
+	cfg.Download.MaxConcurrentProxy = getEnvAsInt("MAX_CONCURRENT_PROXY_DOWNLOADS", 5)
+	cfg.Download.ExtractQueueSize = getEnvAsInt("EXTRACT_QUEUE_SIZE", 50)
+	cfg.Download.ProxyQueueSize = getEnvAsInt("PROXY_QUEUE_SIZE", 20)
+	cfg.Download.RetryAfter = getEnvAsDuration("QUEUE_RETRY_AFTER", 5*time.Second)
 	cfg.Download.Timeout = getEnvAsDuration("DOWNLOAD_TIMEOUT", 30*time.Second)
+	cfg.Download.ProxyTimeout = getEnvAsDuration("PROXY_DOWNLOAD_TIMEOUT", 5*time.Minute)
+	cfg.Download.SpoolDir = getEnv("PROXY_SPOOL_DIR", os.TempDir())
+
+	cfg.Platforms.MaxConcurrent = getEnvAsIntMap("PLATFORM_MAX_CONCURRENT")
+	cfg.Platforms.MinInterval = getEnvAsDurationMap("PLATFORM_MIN_INTERVAL")
+	cfg.Platforms.MaxSlowdown = getEnvAsDuration("PLATFORM_MAX_SLOWDOWN", time.Minute)
+	cfg.Platforms.Timeout = getEnvAsDurationMap("PLATFORM_TIMEOUT")
+	cfg.Platforms.DefaultQuality = getEnvAsPairs("PLATFORM_DEFAULT_QUALITY", ";")
+	cfg.Platforms.YtDlpArgs = getEnvWithPrefix("PLATFORM_YTDLP_ARGS_")
+	cfg.Platforms.ExtractorArgs = getEnvWithPrefix("PLATFORM_EXTRACTOR_ARGS_")
+
+	cfg.YtDlp.Path = getEnv("YTDLP_PATH", "yt-dlp")
+	cfg.YtDlp.BinDir = getEnv("YTDLP_BIN_DIR", "")
+	cfg.YtDlp.MinVersion = getEnv("YTDLP_MIN_VERSION", "")
+	cfg.YtDlp.CheckInterval = getEnvAsDuration("YTDLP_CHECK_INTERVAL", time.Minute)
+
+	cfg.Workers.Size = getEnvAsInt("YTDLP_WORKERS", 2)
+	cfg.Workers.Python = getEnv("YTDLP_WORKER_PYTHON", "python3")
+	cfg.Workers.MaxJobs = getEnvAsInt("YTDLP_WORKER_MAX_JOBS", 100)
+	cfg.Workers.HealthInterval = getEnvAsDuration("YTDLP_WORKER_HEALTH_INTERVAL", 30*time.Second)
+
+	cfg.Quality.Profiles = getEnvAsPairs("QUALITY_PROFILES", ";")
+
+	cfg.Retry.MaxAttempts = getEnvAsInt("EXTRACT_MAX_ATTEMPTS", 3)
+	cfg.Retry.BaseDelay = getEnvAsDuration("EXTRACT_RETRY_BASE_DELAY", time.Second)
+	cfg.Retry.MaxDelay = getEnvAsDuration("EXTRACT_RETRY_MAX_DELAY", 10*time.Second)
+	cfg.Retry.Proxies = getEnvAsList("EXTRACT_RETRY_PROXIES", nil)
+
+	cfg.Jobs.TTL = getEnvAsDuration("JOB_TTL", 24*time.Hour)
+	cfg.Jobs.EventRetention = getEnvAsDuration("PROGRESS_EVENT_RETENTION", 10*time.Minute)
+
+	cfg.Webhooks.Secret = getEnv("WEBHOOK_SECRET", "")
+	cfg.Webhooks.AllowedHosts = getEnvAsList("WEBHOOK_ALLOWED_HOSTS", nil)
+	cfg.Webhooks.MaxAttempts = getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 5)
+	cfg.Webhooks.Backoff = getEnvAsDuration("WEBHOOK_RETRY_BACKOFF", 2*time.Second)
+	cfg.Webhooks.Timeout = getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+
+	cfg.Admin.Token = getEnv("ADMIN_TOKEN", "")
 
 	cfg.UserAgent.RotateAgents = getEnvAsBool("ROTATE_USER_AGENTS", true)
 	cfg.UserAgent.RandomOrder = getEnvAsBool("RANDOM_USER_AGENT_ORDER", true)
 
 	cfg.Environment = getEnv("ENV", "development")
 
+	// Platform defaults may name quality profiles, so validate once those are loaded
+	if err := validatePlatforms(cfg); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
@@ -94,3 +214,89 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getEnvAsBytes parses sizes such as "512MB", "2GB" or a plain byte count
+func getEnvAsBytes(key string, defaultValue int64) int64 {
+	value := strings.ToUpper(strings.TrimSpace(os.Getenv(key)))
+	if value == "" {
+		return defaultValue
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+
+	if intValue, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return intValue * multiplier
+	}
+	return defaultValue
+}
+
+// getEnvAsList parses a comma separated list, ignoring empty entries
+func getEnvAsList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvAsMap parses a comma separated list of key=value pairs such as
+// "instagram=2,twitter=4", ignoring malformed entries
+func getEnvAsMap(key string) map[string]string {
+	return getEnvAsPairs(key, ",")
+}
+
+// getEnvAsPairs is getEnvAsMap with a custom separator, for values that
+// contain commas themselves. Only the first "=" splits name and value.
+func getEnvAsPairs(key, sep string) map[string]string {
+	values := make(map[string]string)
+	for _, item := range strings.Split(os.Getenv(key), sep) {
+		name, value, ok := strings.Cut(item, "=")
+		name, value = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+		if ok && name != "" && value != "" {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+func getEnvAsIntMap(key string) map[string]int {
+	values := make(map[string]int)
+	for name, value := range getEnvAsMap(key) {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			values[name] = intValue
+		}
+	}
+	return values
+}
+
+func getEnvAsDurationMap(key string) map[string]time.Duration {
+	values := make(map[string]time.Duration)
+	for name, value := range getEnvAsMap(key) {
+		if duration, err := time.ParseDuration(value); err == nil {
+			values[name] = duration
+		}
+	}
+	return values
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadPlatformDefaultQuality(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles string
+		defaults string
+		// wantErr is a substring of the expected error, "" when Load succeeds
+		wantErr string
+	}{
+		{"selector", "", "instagram=best[height<=1080]", ""},
+		{"built-in profile", "", "tiktok=balanced", ""},
+		{"custom profile", "phone=best[height<=720]", "instagram=phone", ""},
+		{"custom profile with commas", "mobile=best[height<=720,fps<=30]", "instagram=mobile;tiktok=datasaver", ""},
+		{"unknown profile", "", "instagram=phone", "PLATFORM_DEFAULT_QUALITY instagram"},
+		{"invalid selector", "", "instagram=best[bogus]", "PLATFORM_DEFAULT_QUALITY instagram"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("QUALITY_PROFILES", tt.profiles)
+			t.Setenv("PLATFORM_DEFAULT_QUALITY", tt.defaults)
+
+			cfg, err := Load()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				if len(cfg.Platforms.DefaultQuality) == 0 {
+					t.Errorf("Load() has no platform default qualities")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateYtDlpArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr bool
+	}{
+		{[]string{"--extractor-retries", "3"}, false},
+		{[]string{"--retries=3", "--geo-bypass", "-4"}, false},
+		{[]string{"--add-header", "Accept-Language:en"}, false},
+		{[]string{"--exec", "rm -rf /"}, true},
+		{[]string{"--cookies", "/tmp/cookies.txt"}, true},
+		{[]string{"--ffmpeg-location", "/tmp/x"}, true},
+		{[]string{"--no-simulate"}, true},
+		{[]string{"--geo-bypass=yes"}, true},
+		{[]string{"--referer"}, true},
+		{[]string{"--add-header", "X:y", "https://example.com"}, true},
+	}
+
+	for _, tt := range tests {
+		err := validateYtDlpArgs(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateYtDlpArgs(%q) error = %v, want error %v", tt.args, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"vidtogallery/pkg/quality"
+)
+
+// allowedYtDlpOptions are the yt-dlp options configured arguments may use,
+// mapped to whether they take a value. They only change how yt-dlp reaches
+// the platform; anything that runs commands, reads or writes files, or
+// changes the output the downloader parses is left out.
+var allowedYtDlpOptions = map[string]bool{
+	"--user-agent":                 true,
+	"--add-header":                 true,
+	"--referer":                    true,
+	"--socket-timeout":             true,
+	"--retries":                    true,
+	"--extractor-retries":          true,
+	"--retry-sleep":                true,
+	"--sleep-requests":             true,
+	"--extractor-args":             true,
+	"--impersonate":                true,
+	"--proxy":                      true,
+	"--source-address":             true,
+	"--geo-verification-proxy":     true,
+	"--geo-bypass-country":         true,
+	"--geo-bypass-ip-block":        true,
+	"--xff":                        true,
+	"--geo-bypass":                 false,
+	"--no-geo-bypass":              false,
+	"--force-ipv4":                 false,
+	"--force-ipv6":                 false,
+	"-4":                           false,
+	"-6":                           false,
+	"--legacy-server-connect":      false,
+	"--prefer-insecure":            false,
+	"--no-check-formats":           false,
+	"--check-formats":              false,
+	"--allow-dynamic-mpd":          false,
+	"--ignore-dynamic-mpd":         false,
+	"--hls-split-discontinuity":    false,
+	"--no-hls-split-discontinuity": false,
+}
+
+// extractorArgsPattern matches the IE_KEY:ARG=VALUE[;ARG=VALUE] value of
+// yt-dlp's --extractor-args
+var extractorArgsPattern = regexp.MustCompile(`^[A-Za-z0-9_]+:[A-Za-z0-9_-]+=[^;\s]*(?:;[A-Za-z0-9_-]+=[^;\s]*)*$`)
+
+// getEnvWithPrefix returns the whitespace separated words of every variable
+// named prefix followed by a platform, keyed by the lowercased platform
+func getEnvWithPrefix(prefix string) map[string][]string {
+	values := make(map[string][]string)
+	for _, variable := range os.Environ() {
+		name, value, _ := strings.Cut(variable, "=")
+		platform, ok := strings.CutPrefix(name, prefix)
+		if !ok || platform == "" {
+			continue
+		}
+		if words := strings.Fields(value); len(words) > 0 {
+			values[strings.ToLower(platform)] = words
+		}
+	}
+	return values
+}
+
+// validatePlatforms rejects configured yt-dlp arguments and default
+// qualities that are unsafe or malformed, so a bad setting fails startup
+// instead of every extraction
+func validatePlatforms(cfg *Config) error {
+	var errs []error
+	for _, platform := range sortedKeys(cfg.Platforms.YtDlpArgs) {
+		if err := validateYtDlpArgs(cfg.Platforms.YtDlpArgs[platform]); err != nil {
+			errs = append(errs, fmt.Errorf("PLATFORM_YTDLP_ARGS_%s: %w", strings.ToUpper(platform), err))
+		}
+	}
+	for _, platform := range sortedKeys(cfg.Platforms.ExtractorArgs) {
+		for _, value := range cfg.Platforms.ExtractorArgs[platform] {
+			if !extractorArgsPattern.MatchString(value) {
+				errs = append(errs, fmt.Errorf("PLATFORM_EXTRACTOR_ARGS_%s: %q is not IE_KEY:ARG=VALUE[;ARG=VALUE]", strings.ToUpper(platform), value))
+			}
+		}
+	}
+
+	// Default qualities may name profiles, so resolve them like requests do.
+	// Invalid profiles are reported when the downloader configures them.
+	qualityManager := quality.NewManager()
+	_ = qualityManager.ConfigureProfiles(cfg.Quality.Profiles)
+	for _, platform := range sortedKeys(cfg.Platforms.DefaultQuality) {
+		if _, err := qualityManager.Resolve(cfg.Platforms.DefaultQuality[platform]); err != nil {
+			errs = append(errs, fmt.Errorf("PLATFORM_DEFAULT_QUALITY %s: %w", platform, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateYtDlpArgs checks that args are allowed options, each followed by
+// its value if it takes one, either inline after "=" or as the next word
+func validateYtDlpArgs(args []string) error {
+	for i := 0; i < len(args); i++ {
+		name, value, inline := strings.Cut(args[i], "=")
+		takesValue, ok := allowedYtDlpOptions[name]
+		if !ok {
+			return fmt.Errorf("option %q is not allowed", name)
+		}
+
+		switch {
+		case !takesValue && inline:
+			return fmt.Errorf("option %s takes no value", name)
+		case takesValue && inline && value == "":
+			return fmt.Errorf("option %s needs a value", name)
+		case takesValue && !inline:
+			if i+1 >= len(args) {
+				return fmt.Errorf("option %s needs a value", name)
+			}
+			i++
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
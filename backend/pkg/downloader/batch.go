@@ -0,0 +1,83 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"vidtogallery/internal/models"
+)
+
+// ProcessBatch extracts every item concurrently, with at most as many in
+// flight as there are workers, and returns one result per item in order.
+// Items with the same canonical URL and quality are extracted once. A failed
+// item only fails its own result; its platform's extraction timeout starts once
+// the item is picked up.
+func (s *Service) ProcessBatch(ctx context.Context, items []models.BatchItem) []models.BatchItemResult {
+	results := make([]models.BatchItemResult, len(items))
+	first := make(map[string]int, len(items))
+	var unique []int
+
+	for i, item := range items {
+		url := strings.TrimSpace(item.URL)
+		results[i] = models.BatchItemResult{URL: url, Quality: item.Quality}
+
+		if url == "" {
+			results[i].Error = "URL is required"
+			continue
+		}
+		spec, err := s.ResolveQuality(url, item.Quality)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Quality = spec.String()
+		results[i].CanonicalURL = CanonicalURL(url)
+
+		key := fmt.Sprintf("%s:%s", results[i].CanonicalURL, spec)
+		if j, seen := first[key]; seen {
+			results[i].DuplicateOf = &j
+			continue
+		}
+		first[key] = i
+		unique = append(unique, i)
+	}
+
+	slots := make(chan struct{}, s.extractPool.Size())
+	var wg sync.WaitGroup
+	for _, i := range unique {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Error = ctx.Err().Error()
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *models.BatchItemResult) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			itemCtx, cancel := context.WithTimeout(ctx, s.Timeout(result.URL))
+			defer cancel()
+
+			video, err := s.ProcessURLWithQuality(itemCtx, result.URL, result.Quality)
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+			result.Result = video
+		}(&results[i])
+	}
+	wg.Wait()
+
+	for i := range results {
+		if j := results[i].DuplicateOf; j != nil {
+			results[i].Result = results[*j].Result
+			results[i].Error = results[*j].Error
+		}
+	}
+
+	return results
+}
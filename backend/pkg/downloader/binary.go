@@ -0,0 +1,297 @@
+package downloader
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"vidtogallery/internal/models"
+	"vidtogallery/pkg/config"
+)
+
+// smokeFixture is a local page with one video that candidate binaries must
+// be able to extract before they are used
+//
+//go:embed smoke_fixture.html
+var smokeFixture []byte
+
+const (
+	// versionTimeout bounds yt-dlp --version
+	versionTimeout = 30 * time.Second
+	// smokeTestTimeout bounds the extraction of the smoke test fixture
+	smokeTestTimeout = time.Minute
+)
+
+// ytdlpBinary is a yt-dlp executable and the version it reported. err is
+// set when the binary cannot be used, e.g. because it is too old.
+type ytdlpBinary struct {
+	path    string
+	version string
+	err     error
+}
+
+// binaryManager tracks the yt-dlp binary extractions run. A newer binary
+// dropped into the configured directory replaces it without a restart once
+// it passes a smoke test, and binaries older than the minimum version are
+// refused.
+type binaryManager struct {
+	dir        string
+	minVersion string
+	logger     *logrus.Logger
+
+	active atomic.Pointer[ytdlpBinary]
+	// onSwap is called with each binary that replaces the active one
+	onSwap func(ytdlpBinary)
+
+	mu sync.Mutex
+	// seen maps candidate paths to the modification time and size they were
+	// checked with, so unchanged files are not tested again
+	seen map[string]string
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// newBinaryManager detects the version of the configured binary, switches
+// to a newer one from the binary directory if there is one, and starts
+// watching that directory
+func newBinaryManager(cfg *config.Config, logger *logrus.Logger, onSwap func(ytdlpBinary)) *binaryManager {
+	m := &binaryManager{
+		dir:        cfg.YtDlp.BinDir,
+		minVersion: cfg.YtDlp.MinVersion,
+		logger:     logger,
+		onSwap:     onSwap,
+		seen:       make(map[string]string),
+		stop:       make(chan struct{}),
+	}
+
+	binary := m.inspect(cfg.YtDlp.Path)
+	m.active.Store(&binary)
+	if binary.err != nil {
+		logger.WithError(binary.err).WithField("path", binary.path).Error("Configured yt-dlp binary is not usable")
+	} else {
+		logger.WithField("path", binary.path).WithField("version", binary.version).Info("Using yt-dlp")
+	}
+
+	if m.dir != "" {
+		m.check()
+		if cfg.YtDlp.CheckInterval > 0 {
+			go m.watch(cfg.YtDlp.CheckInterval)
+		}
+	}
+	return m
+}
+
+// current returns the binary extractions should run
+func (m *binaryManager) current() ytdlpBinary {
+	return *m.active.Load()
+}
+
+// inspect detects the version of the binary at path and whether it meets
+// the minimum version
+func (m *binaryManager) inspect(path string) ytdlpBinary {
+	binary := ytdlpBinary{path: path}
+
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		binary.err = fmt.Errorf("yt-dlp at %s failed to report its version: %w", path, err)
+		return binary
+	}
+	binary.version = strings.TrimSpace(string(output))
+
+	if m.minVersion != "" && compareVersions(binary.version, m.minVersion) < 0 {
+		binary.err = fmt.Errorf("yt-dlp %s at %s is older than the minimum version %s", binary.version, path, m.minVersion)
+	}
+	return binary
+}
+
+func (m *binaryManager) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.check()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// check switches to the newest binary in the binary directory that is newer
+// than the active one, meets the minimum version and passes the smoke test.
+// Files starting with a dot are ignored, so binaries can be copied in under
+// a hidden name and renamed once complete.
+func (m *binaryManager) check() {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		m.logger.WithError(err).WithField("dir", m.dir).Warn("Failed to read yt-dlp binary directory")
+		return
+	}
+
+	active := m.current()
+	var candidates []ytdlpBinary
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+
+		path := filepath.Join(m.dir, entry.Name())
+		stamp := fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+		m.mu.Lock()
+		unchanged := m.seen[path] == stamp
+		m.seen[path] = stamp
+		m.mu.Unlock()
+		if unchanged {
+			continue
+		}
+
+		candidate := m.inspect(path)
+		if candidate.err != nil {
+			m.logger.WithError(candidate.err).Warn("Ignoring yt-dlp binary")
+			continue
+		}
+		if active.err == nil && compareVersions(candidate.version, active.version) <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	// Newest first, falling back to older candidates that pass the smoke test
+	sort.Slice(candidates, func(i, j int) bool {
+		return compareVersions(candidates[i].version, candidates[j].version) > 0
+	})
+	for _, candidate := range candidates {
+		if err := smokeTest(candidate.path); err != nil {
+			m.logger.WithError(err).WithField("path", candidate.path).WithField("version", candidate.version).Warn("yt-dlp binary failed its smoke test, not switching")
+			continue
+		}
+
+		m.active.Store(&candidate)
+		m.logger.WithFields(logrus.Fields{
+			"path":     candidate.path,
+			"version":  candidate.version,
+			"previous": active.version,
+		}).Info("Switched to newer yt-dlp binary")
+
+		if m.onSwap != nil {
+			m.onSwap(candidate)
+		}
+		return
+	}
+}
+
+// status describes the active binary for the health check
+func (m *binaryManager) status() models.YtDlpStatus {
+	binary := m.current()
+	status := models.YtDlpStatus{
+		Path:       binary.path,
+		Version:    binary.version,
+		MinVersion: m.minVersion,
+	}
+	if binary.err != nil {
+		status.Error = binary.err.Error()
+	}
+	return status
+}
+
+// Close stops watching the binary directory
+func (m *binaryManager) Close() {
+	if m == nil {
+		return
+	}
+	m.stopOnce.Do(func() { close(m.stop) })
+}
+
+// smokeTest extracts the bundled fixture page with the binary at path
+func smokeTest(path string) error {
+	dir, err := os.MkdirTemp("", "vidtogallery-smoke-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	page := filepath.Join(dir, "fixture.html")
+	if err := os.WriteFile(page, smokeFixture, 0o644); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), smokeTestTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path,
+		"--dump-json",
+		"--no-warnings",
+		"--no-playlist",
+		"--enable-file-urls",
+		"file://"+page,
+	).Output()
+	if err != nil {
+		return fmt.Errorf("extracting the fixture failed: %w", err)
+	}
+
+	var info UniversalYtDlpInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		return fmt.Errorf("invalid output for the fixture: %w", err)
+	}
+	if info.URL == "" && len(info.Formats) == 0 {
+		return fmt.Errorf("no video found in the fixture")
+	}
+	return nil
+}
+
+// compareVersions compares yt-dlp versions such as "2024.12.13" or nightly
+// "2025.01.02.232701" part by part, numerically where both parts are numbers
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+
+		xn, xErr := strconv.Atoi(x)
+		yn, yErr := strconv.Atoi(y)
+		switch {
+		case x == y:
+			continue
+		case x == "":
+			return -1
+		case y == "":
+			return 1
+		case xErr == nil && yErr == nil:
+			if xn < yn {
+				return -1
+			}
+			if xn > yn {
+				return 1
+			}
+		default:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
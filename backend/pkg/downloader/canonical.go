@@ -0,0 +1,58 @@
+package downloader
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// hostAliases maps alternate hostnames to the one used in canonical URLs
+var hostAliases = map[string]string{
+	"x.com":      "twitter.com",
+	"instagr.am": "instagram.com",
+}
+
+var (
+	instagramPath = regexp.MustCompile(`^/(?:[^/]+/)?(?:p|reel|reels|tv)/([A-Za-z0-9_-]+)`)
+	twitterPath   = regexp.MustCompile(`^/[^/]+/status/(\d+)`)
+)
+
+// CanonicalURL normalizes a social media URL so that links to the same post
+// share one cache and deduplication key. Tracking query parameters, fragments,
+// usernames in tweet links and host aliases are removed. URLs that cannot be
+// parsed are returned trimmed but otherwise unchanged.
+func CanonicalURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+
+	withScheme := rawURL
+	if !strings.Contains(withScheme, "://") {
+		withScheme = "https://" + withScheme
+	}
+
+	u, err := url.Parse(withScheme)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range []string{"www.", "mobile.", "m."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	if alias, ok := hostAliases[host]; ok {
+		host = alias
+	}
+
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+	switch host {
+	case "instagram.com":
+		if m := instagramPath.FindStringSubmatch(path); m != nil {
+			path = "/p/" + m[1]
+		}
+	case "twitter.com":
+		if m := twitterPath.FindStringSubmatch(path); m != nil {
+			path = "/i/status/" + m[1]
+		}
+	}
+
+	return "https://" + host + path
+}
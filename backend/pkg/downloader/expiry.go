@@ -0,0 +1,50 @@
+package downloader
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// urlExpiryMargin is how long before a signed CDN URL expires we stop handing it out
+const urlExpiryMargin = 5 * time.Minute
+
+// videoURLExpiry returns the expiry time encoded in a signed CDN URL, if any.
+//
+// Platforms sign their media URLs differently:
+//   - Instagram/Facebook CDN: "oe" holds a hex unix timestamp
+//   - TikTok: "expire" or "x-expires" hold a decimal unix timestamp
+//   - CloudFront-style URLs: "Expires" holds a decimal unix timestamp
+//   - S3 presigned URLs: "X-Amz-Date" plus "X-Amz-Expires" seconds
+func videoURLExpiry(videoURL string) (time.Time, bool) {
+	u, err := url.Parse(videoURL)
+	if err != nil {
+		return time.Time{}, false
+	}
+	query := u.Query()
+
+	if oe := query.Get("oe"); oe != "" {
+		if ts, err := strconv.ParseInt(oe, 16, 64); err == nil {
+			return time.Unix(ts, 0), true
+		}
+	}
+
+	for _, key := range []string{"expire", "x-expires", "Expires"} {
+		if value := query.Get(key); value != "" {
+			if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return time.Unix(ts, 0), true
+			}
+		}
+	}
+
+	if date, expires := query.Get("X-Amz-Date"), query.Get("X-Amz-Expires"); date != "" && expires != "" {
+		signedAt, err := time.Parse("20060102T150405Z", date)
+		seconds, convErr := strconv.Atoi(strings.TrimSpace(expires))
+		if err == nil && convErr == nil {
+			return signedAt.Add(time.Duration(seconds) * time.Second), true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,231 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+)
+
+// fetchGroup tracks in-flight upstream downloads so concurrent proxy requests
+// for the same video URL share a single connection to the CDN
+type fetchGroup struct {
+	mu       sync.Mutex
+	spoolDir string
+	fetches  map[string]*sharedFetch
+}
+
+// sharedFetch is one upstream download spooled to a temporary file. Readers
+// follow the file as it grows, so late joiners catch up from the start.
+type sharedFetch struct {
+	path   string
+	ready  chan struct{}
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	size      int64
+	written   int64
+	done      bool
+	err       error
+	notify    chan struct{}
+	readers   int
+	abandoned bool
+}
+
+func newFetchGroup(spoolDir string) *fetchGroup {
+	return &fetchGroup{
+		spoolDir: spoolDir,
+		fetches:  make(map[string]*sharedFetch),
+	}
+}
+
+// join returns a reader for the in-flight download of key, starting one with
+// start if none is running. start receives the spool file to write into and
+// must call fetch.begin once upstream headers arrive and fetch.finish when done.
+func (g *fetchGroup) join(ctx context.Context, key string, start func(ctx context.Context, fetch *sharedFetch, spool *os.File)) (*spoolReader, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fetch, ok := g.fetches[key]
+	if ok && !fetch.acquire() {
+		// Finished or abandoned but not yet removed from the group
+		ok = false
+	}
+	if !ok {
+		spool, err := os.CreateTemp(g.spoolDir, "proxy-*.part")
+		if err != nil {
+			return nil, err
+		}
+
+		fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		fetch = &sharedFetch{
+			path:    spool.Name(),
+			ready:   make(chan struct{}),
+			cancel:  cancel,
+			size:    -1,
+			notify:  make(chan struct{}),
+			readers: 1,
+		}
+		g.fetches[key] = fetch
+
+		go func() {
+			start(fetchCtx, fetch, spool)
+			cancel()
+
+			g.mu.Lock()
+			if g.fetches[key] == fetch {
+				delete(g.fetches, key)
+			}
+			g.mu.Unlock()
+		}()
+	}
+
+	file, err := os.Open(fetch.path)
+	if err != nil {
+		fetch.release()
+		return nil, err
+	}
+
+	return &spoolReader{fetch: fetch, file: file}, nil
+}
+
+// acquire registers another reader unless the download has already finished
+// or been abandoned by its last reader
+func (f *sharedFetch) acquire() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.done || f.abandoned {
+		return false
+	}
+	f.readers++
+	return true
+}
+
+// begin records the upstream content length and releases waiting readers
+func (f *sharedFetch) begin(size int64) {
+	f.mu.Lock()
+	f.size = size
+	f.mu.Unlock()
+	close(f.ready)
+}
+
+// advance records n more bytes written to the spool file
+func (f *sharedFetch) advance(n int) {
+	f.mu.Lock()
+	f.written += int64(n)
+	f.broadcast()
+	f.mu.Unlock()
+}
+
+// finish marks the download complete. If it fails before begin was called,
+// readers waiting for headers are released with err.
+func (f *sharedFetch) finish(err error) {
+	f.mu.Lock()
+	f.done = true
+	f.err = err
+	f.broadcast()
+	removable := f.readers == 0
+	f.mu.Unlock()
+
+	select {
+	case <-f.ready:
+	default:
+		close(f.ready)
+	}
+
+	if removable {
+		os.Remove(f.path)
+	}
+}
+
+// broadcast wakes every reader waiting for progress. The caller must hold f.mu.
+func (f *sharedFetch) broadcast() {
+	close(f.notify)
+	f.notify = make(chan struct{})
+}
+
+// release drops a reader, cancelling the upstream download when nobody is left
+func (f *sharedFetch) release() {
+	f.mu.Lock()
+	f.readers--
+	last := f.readers == 0
+	done := f.done
+	if last && !done {
+		f.abandoned = true
+	}
+	f.mu.Unlock()
+
+	if !last {
+		return
+	}
+	if done {
+		os.Remove(f.path)
+	} else {
+		f.cancel()
+	}
+}
+
+// spoolReader streams a shared download from its spool file, blocking until
+// more bytes arrive or the upstream download finishes
+type spoolReader struct {
+	fetch  *sharedFetch
+	file   *os.File
+	offset int64
+	once   sync.Once
+}
+
+// wait blocks until upstream headers arrive and returns the content length, or -1 if unknown
+func (r *spoolReader) wait(ctx context.Context) (int64, error) {
+	select {
+	case <-r.fetch.ready:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	r.fetch.mu.Lock()
+	defer r.fetch.mu.Unlock()
+
+	if r.fetch.done && r.fetch.err != nil && r.fetch.written == 0 {
+		return 0, r.fetch.err
+	}
+	return r.fetch.size, nil
+}
+
+func (r *spoolReader) Read(p []byte) (int, error) {
+	for {
+		r.fetch.mu.Lock()
+		written, done, err, notify := r.fetch.written, r.fetch.done, r.fetch.err, r.fetch.notify
+		r.fetch.mu.Unlock()
+
+		if r.offset < written {
+			if available := written - r.offset; int64(len(p)) > available {
+				p = p[:available]
+			}
+			n, readErr := r.file.ReadAt(p, r.offset)
+			r.offset += int64(n)
+			if readErr == io.EOF {
+				readErr = nil
+			}
+			return n, readErr
+		}
+
+		if done {
+			if err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+
+		<-notify
+	}
+}
+
+func (r *spoolReader) Close() error {
+	var err error
+	r.once.Do(func() {
+		err = r.file.Close()
+		r.fetch.release()
+	})
+	return err
+}
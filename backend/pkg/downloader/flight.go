@@ -0,0 +1,122 @@
+package downloader
+
+import (
+	"context"
+	"sync"
+
+	"vidtogallery/internal/models"
+)
+
+// flightGroup coalesces concurrent extractions that share a key into a single run
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done    chan struct{}
+	video   *models.VideoResponse
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+
+	// Progress of the run is forwarded to every waiter that asked for it
+	listeners    map[int]ProgressFunc
+	nextListener int
+	progress     *models.Progress
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{
+		calls: make(map[string]*flightCall),
+	}
+}
+
+// do runs fn once for all concurrent callers with the same key and reports
+// whether the result was shared with an earlier caller. fn runs on a context
+// detached from the caller that started it, so a leader that disconnects does
+// not fail everyone else; fn is only cancelled once every waiter has gone.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) (*models.VideoResponse, error)) (*models.VideoResponse, bool, error) {
+	g.mu.Lock()
+	call, shared := g.calls[key]
+	if !shared {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &flightCall{
+			done:      make(chan struct{}),
+			cancel:    cancel,
+			listeners: make(map[int]ProgressFunc),
+		}
+		g.calls[key] = call
+		callCtx = WithProgress(callCtx, func(progress models.Progress) {
+			g.report(call, progress)
+		})
+
+		go func() {
+			call.video, call.err = fn(callCtx)
+			cancel()
+
+			g.mu.Lock()
+			g.forget(key, call)
+			g.mu.Unlock()
+
+			close(call.done)
+		}()
+	}
+	call.waiters++
+
+	listener := -1
+	var current *models.Progress
+	onProgress, ok := progressFrom(ctx)
+	if ok {
+		listener = call.nextListener
+		call.nextListener++
+		call.listeners[listener] = onProgress
+		current = call.progress
+	}
+	g.mu.Unlock()
+
+	if current != nil {
+		// Late joiners start from the current stage
+		onProgress(*current)
+	}
+
+	select {
+	case <-call.done:
+		return call.video, shared, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		delete(call.listeners, listener)
+		if call.waiters == 0 {
+			// Nobody is left to receive the result; stop the work and let the
+			// next caller start afresh instead of joining a cancelled run
+			call.cancel()
+			g.forget(key, call)
+		}
+		g.mu.Unlock()
+		return nil, shared, ctx.Err()
+	}
+}
+
+// forget removes call from the group if it is still the active call for key.
+// The caller must hold g.mu.
+func (g *flightGroup) forget(key string, call *flightCall) {
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
+
+// report records progress of call and forwards it to its listeners
+func (g *flightGroup) report(call *flightCall, progress models.Progress) {
+	g.mu.Lock()
+	call.progress = &progress
+	listeners := make([]ProgressFunc, 0, len(call.listeners))
+	for _, fn := range call.listeners {
+		listeners = append(listeners, fn)
+	}
+	g.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(progress)
+	}
+}
@@ -0,0 +1,51 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// cachedInfo returns the yt-dlp info cached for the post at url, so
+// listing qualities and extracting any of them share one yt-dlp run
+func (s *Service) cachedInfo(ctx context.Context, url string) (*UniversalYtDlpInfo, bool) {
+	data, found := s.cacheService.GetInfo(ctx, CanonicalURL(url))
+	if !found {
+		return nil, false
+	}
+
+	var info UniversalYtDlpInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		s.logger.WithError(err).WithField("url", url).Warn("Failed to decode cached video info")
+		return nil, false
+	}
+	return &info, true
+}
+
+// cacheInfo stores the yt-dlp info of the post at url until its earliest
+// signed URL is about to expire, capped by the configured info TTL
+func (s *Service) cacheInfo(ctx context.Context, url string, info *UniversalYtDlpInfo) {
+	ttl := s.infoTTL
+	now := time.Now()
+	urls := []string{info.URL}
+	for _, format := range info.Formats {
+		urls = append(urls, format.URL)
+	}
+	for _, videoURL := range urls {
+		if expiresAt, ok := videoURLExpiry(videoURL); ok {
+			ttl = min(ttl, expiresAt.Sub(now)-urlExpiryMargin)
+		}
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		s.logger.WithError(err).WithField("url", url).Warn("Failed to encode video info")
+		return
+	}
+	if err := s.cacheService.SetInfo(ctx, CanonicalURL(url), data, info.Extractor, ttl); err != nil {
+		s.logger.WithError(err).WithField("url", url).Warn("Failed to cache video info")
+	}
+}
@@ -0,0 +1,128 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown is returned for work started after the service was stopped
+var ErrShuttingDown = errors.New("downloader is shutting down")
+
+// stopGrace is how long cancelled work may take to exit after Shutdown
+// gives up waiting for it, enough for yt-dlp to be killed after SIGTERM
+const stopGrace = killDelay + 2*time.Second
+
+// lifecycle tracks in-flight extractions and fetches so shutdown can wait
+// for them and cancel whatever is left when the deadline passes
+type lifecycle struct {
+	root context.Context
+	stop context.CancelFunc
+
+	mu       sync.Mutex
+	inflight int
+	stopped  bool
+	// idle is closed whenever no work is in flight
+	idle chan struct{}
+}
+
+func newLifecycle() *lifecycle {
+	root, stop := context.WithCancel(context.Background())
+	idle := make(chan struct{})
+	close(idle)
+
+	return &lifecycle{root: root, stop: stop, idle: idle}
+}
+
+// begin registers in-flight work. The returned context is also cancelled
+// when the service stops; done must be called once the work has finished.
+func (l *lifecycle) begin(ctx context.Context) (context.Context, func(), error) {
+	l.mu.Lock()
+	if l.stopped {
+		l.mu.Unlock()
+		return nil, nil, ErrShuttingDown
+	}
+	if l.inflight == 0 {
+		l.idle = make(chan struct{})
+	}
+	l.inflight++
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	unbind := context.AfterFunc(l.root, cancel)
+
+	return ctx, func() {
+		unbind()
+		cancel()
+
+		l.mu.Lock()
+		l.inflight--
+		if l.inflight == 0 {
+			close(l.idle)
+		}
+		l.mu.Unlock()
+	}, nil
+}
+
+// stopIfIdle stops accepting work if none is in flight and reports whether
+// it did
+func (l *lifecycle) stopIfIdle() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inflight > 0 {
+		return false
+	}
+	l.stopped = true
+	return true
+}
+
+func (l *lifecycle) idleChan() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.idle
+}
+
+// Track registers background work started outside the service, such as a
+// batch whose results go to a callback, so Shutdown waits for it like for
+// extractions. done must be called once the work has finished.
+func (s *Service) Track(ctx context.Context) (context.Context, func(), error) {
+	return s.lifecycle.begin(ctx)
+}
+
+// Shutdown waits until no extraction or proxy fetch is in flight, then stops
+// the yt-dlp workers and binary updates. When ctx ends first, the remaining work is cancelled,
+// which kills its yt-dlp processes and upstream connections, and ctx.Err()
+// is returned.
+func (s *Service) Shutdown(ctx context.Context) error {
+	defer s.downloader.workers.Close()
+	defer s.downloader.binaries.Close()
+
+	// Work may start while waiting, so only stop once nothing is in flight
+wait:
+	for {
+		select {
+		case <-s.lifecycle.idleChan():
+			if s.lifecycle.stopIfIdle() {
+				return nil
+			}
+		case <-ctx.Done():
+			break wait
+		}
+	}
+
+	s.lifecycle.mu.Lock()
+	s.lifecycle.stopped = true
+	remaining := s.lifecycle.inflight
+	s.lifecycle.mu.Unlock()
+
+	s.logger.WithField("remaining", remaining).Warn("Shutdown deadline reached, cancelling in-flight work")
+	s.lifecycle.stop()
+
+	select {
+	case <-s.lifecycle.idleChan():
+	case <-time.After(stopGrace):
+		s.logger.Warn("In-flight work did not exit after cancellation")
+	}
+	return ctx.Err()
+}
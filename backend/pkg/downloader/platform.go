@@ -0,0 +1,185 @@
+package downloader
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"vidtogallery/pkg/config"
+)
+
+// rateLimitPattern matches yt-dlp failures caused by a platform rate limiting us
+var rateLimitPattern = regexp.MustCompile(`(?i)HTTP Error 429|too many requests|rate.?limit`)
+
+// minSlowdown is the extra spacing added after the first rate limit response
+const minSlowdown = time.Second
+
+// platformLimiter caps concurrent extractions per platform and spaces out
+// their start times, so a burst of links for one platform neither trips its
+// rate limiter nor takes every worker away from the others. Spacing grows
+// while a platform answers with 429s and shrinks again as requests succeed.
+type platformLimiter struct {
+	maxSlowdown time.Duration
+	logger      *logrus.Logger
+
+	mu        sync.Mutex
+	platforms map[string]*platformLimit
+	// defaults used for platforms without their own configuration
+	maxConcurrent map[string]int
+	minInterval   map[string]time.Duration
+}
+
+// platformLimit is the state of one platform. Pacing is a token bucket
+// holding a single token that refills every interval plus slowdown.
+type platformLimit struct {
+	slots    chan struct{} // nil when concurrency is not capped
+	interval time.Duration
+
+	mu       sync.Mutex
+	next     time.Time
+	slowdown time.Duration
+}
+
+func newPlatformLimiter(cfg *config.Config, logger *logrus.Logger) *platformLimiter {
+	for platform := range cfg.Platforms.MaxConcurrent {
+		if _, ok := platformPatterns[platform]; !ok {
+			logger.WithField("platform", platform).Warn("Ignoring concurrency limit for unknown platform")
+		}
+	}
+	for platform := range cfg.Platforms.MinInterval {
+		if _, ok := platformPatterns[platform]; !ok {
+			logger.WithField("platform", platform).Warn("Ignoring request interval for unknown platform")
+		}
+	}
+
+	return &platformLimiter{
+		maxSlowdown:   cfg.Platforms.MaxSlowdown,
+		logger:        logger,
+		platforms:     make(map[string]*platformLimit),
+		maxConcurrent: cfg.Platforms.MaxConcurrent,
+		minInterval:   cfg.Platforms.MinInterval,
+	}
+}
+
+func (l *platformLimiter) limit(platform string) *platformLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, ok := l.platforms[platform]
+	if !ok {
+		limit = &platformLimit{interval: l.minInterval[platform]}
+		if n := l.maxConcurrent[platform]; n > 0 {
+			limit.slots = make(chan struct{}, n)
+		}
+		l.platforms[platform] = limit
+	}
+	return limit
+}
+
+// acquire waits for a free slot and the next pacing token of platform. The
+// returned release must be called with the outcome of the extraction.
+func (l *platformLimiter) acquire(ctx context.Context, platform string) (func(err error), error) {
+	limit := l.limit(platform)
+
+	if limit.slots != nil {
+		select {
+		case limit.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if err := sleepContext(ctx, limit.reserve(time.Now())); err != nil {
+		limit.releaseSlot()
+		return nil, err
+	}
+
+	return func(err error) {
+		l.record(platform, limit, err)
+		limit.releaseSlot()
+	}, nil
+}
+
+// tryAcquire is acquire for background work: it fails instead of waiting
+// when platform has no free slot or pacing token right now
+func (l *platformLimiter) tryAcquire(platform string) (func(err error), bool) {
+	limit := l.limit(platform)
+
+	if limit.slots != nil {
+		select {
+		case limit.slots <- struct{}{}:
+		default:
+			return nil, false
+		}
+	}
+
+	if !limit.tryReserve(time.Now()) {
+		limit.releaseSlot()
+		return nil, false
+	}
+
+	return func(err error) {
+		l.record(platform, limit, err)
+		limit.releaseSlot()
+	}, true
+}
+
+// record adjusts the slowdown of platform after an extraction finished with err
+func (l *platformLimiter) record(platform string, limit *platformLimit, err error) {
+	limit.mu.Lock()
+	defer limit.mu.Unlock()
+
+	if err != nil && rateLimitPattern.MatchString(err.Error()) {
+		limit.slowdown *= 2
+		if limit.slowdown < minSlowdown {
+			limit.slowdown = minSlowdown
+		}
+		if limit.slowdown > l.maxSlowdown {
+			limit.slowdown = l.maxSlowdown
+		}
+		l.logger.WithField("platform", platform).WithField("slowdown", limit.slowdown).Warn("Platform is rate limiting, slowing down")
+		return
+	}
+
+	if err == nil && limit.slowdown > 0 {
+		limit.slowdown /= 2
+		if limit.slowdown < minSlowdown {
+			limit.slowdown = 0
+			l.logger.WithField("platform", platform).Info("Platform recovered from rate limiting")
+		}
+	}
+}
+
+// reserve takes the next pacing token and returns how long to wait for it
+func (p *platformLimit) reserve(now time.Time) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	slot := p.next
+	if slot.Before(now) {
+		slot = now
+	}
+	p.next = slot.Add(p.interval + p.slowdown)
+	return slot.Sub(now)
+}
+
+// tryReserve takes the pacing token only if it is available now
+func (p *platformLimit) tryReserve(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next.After(now) {
+		return false
+	}
+	p.next = now.Add(p.interval + p.slowdown)
+	return true
+}
+
+func (p *platformLimit) releaseSlot() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
@@ -0,0 +1,142 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"vidtogallery/internal/models"
+)
+
+// ErrQueueFull is returned when every worker is busy and the wait queue is full
+var ErrQueueFull = errors.New("too many requests are waiting for a worker")
+
+// Pool runs extractions or proxy fetches on a fixed set of worker goroutines,
+// with a bounded number of callers allowed to wait for a free worker
+type Pool struct {
+	tasks     chan *task
+	workers   int
+	queueSize int
+	busy      atomic.Int64
+	waiting   atomic.Int64
+	wg        sync.WaitGroup
+}
+
+type task struct {
+	fn   func()
+	done chan struct{}
+	err  error
+}
+
+// NewPool starts a pool with the given number of workers that lets at most
+// queueSize callers wait for one
+func NewPool(workers int, queueSize int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	p := &Pool{
+		// Unbuffered: a hand-off only succeeds when a worker is idle
+		tasks:     make(chan *task),
+		workers:   workers,
+		queueSize: queueSize,
+	}
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for t := range p.tasks {
+		p.busy.Add(1)
+		t.run()
+		p.busy.Add(-1)
+	}
+}
+
+func (t *task) run() {
+	defer close(t.done)
+	defer func() {
+		if r := recover(); r != nil {
+			t.err = fmt.Errorf("worker panic: %v", r)
+		}
+	}()
+	t.fn()
+}
+
+// Run executes fn on a worker and waits for it to return. When no worker is
+// idle it queues behind other callers, failing with ErrQueueFull right away
+// if the queue is full, or with ctx.Err() if ctx ends before a worker becomes
+// free. Once started, fn is expected to observe ctx itself.
+func (p *Pool) Run(ctx context.Context, fn func()) error {
+	t := &task{fn: fn, done: make(chan struct{})}
+
+	select {
+	case p.tasks <- t:
+	default:
+		if err := p.enqueue(ctx, t); err != nil {
+			return err
+		}
+	}
+
+	<-t.done
+	return t.err
+}
+
+// enqueue waits for a worker to take t, holding a queue slot meanwhile
+func (p *Pool) enqueue(ctx context.Context, t *task) error {
+	if p.waiting.Add(1) > int64(p.queueSize) {
+		p.waiting.Add(-1)
+		return ErrQueueFull
+	}
+	defer p.waiting.Add(-1)
+
+	select {
+	case p.tasks <- t:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryGo starts fn on a worker only if one is idle right now, without waiting
+// for it to finish. It reports whether fn was started.
+func (p *Pool) TryGo(fn func()) bool {
+	t := &task{fn: fn, done: make(chan struct{})}
+
+	select {
+	case p.tasks <- t:
+		return true
+	default:
+		return false
+	}
+}
+
+// Busy returns the number of workers currently running a task
+func (p *Pool) Busy() int {
+	return int(p.busy.Load())
+}
+
+// Size returns the total number of workers
+func (p *Pool) Size() int {
+	return p.workers
+}
+
+// Stats reports worker usage and queue depth
+func (p *Pool) Stats() models.PoolStats {
+	return models.PoolStats{
+		Workers:   p.workers,
+		Busy:      p.Busy(),
+		Queued:    int(p.waiting.Load()),
+		QueueSize: p.queueSize,
+	}
+}
@@ -0,0 +1,81 @@
+package downloader
+
+import (
+	"context"
+	"io"
+
+	"vidtogallery/internal/models"
+)
+
+// progressInterval is how many bytes are copied between byte progress reports
+const progressInterval = 512 * 1024
+
+// ProgressFunc receives stage changes and byte counts while work runs
+type ProgressFunc func(models.Progress)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose work reports progress to fn
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func progressFrom(ctx context.Context) (ProgressFunc, bool) {
+	fn, ok := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn, ok
+}
+
+func reportProgress(ctx context.Context, progress models.Progress) {
+	if fn, ok := progressFrom(ctx); ok {
+		fn(progress)
+	}
+}
+
+// progressReader reports the bytes read through it as fetching progress
+type progressReader struct {
+	io.ReadCloser
+	report   ProgressFunc
+	progress models.Progress
+	reported int64
+	eof      bool
+}
+
+// TrackProgress wraps a video body of the given size (-1 if unknown) so every
+// progressInterval bytes read are reported to fn. Closing the body reports a
+// final update, with the done stage if the whole body was read.
+func TrackProgress(body io.ReadCloser, size int64, fn ProgressFunc) io.ReadCloser {
+	r := &progressReader{
+		ReadCloser: body,
+		report:     fn,
+		progress:   models.Progress{Stage: models.StageFetching},
+	}
+	if size > 0 {
+		r.progress.TotalBytes = size
+	}
+	fn(r.progress)
+	return r
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.progress.Bytes += int64(n)
+	if err == io.EOF {
+		r.eof = true
+	}
+	if r.progress.Bytes-r.reported >= progressInterval {
+		r.reported = r.progress.Bytes
+		r.report(r.progress)
+	}
+	return n, err
+}
+
+func (r *progressReader) Close() error {
+	err := r.ReadCloser.Close()
+
+	// Content-Length aware writers stop at the size without reading EOF
+	if r.eof || (r.progress.TotalBytes > 0 && r.progress.Bytes >= r.progress.TotalBytes) {
+		r.progress.Stage = models.StageDone
+	}
+	r.report(r.progress)
+	return err
+}
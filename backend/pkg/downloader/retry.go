@@ -0,0 +1,102 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"regexp"
+	"time"
+
+	"vidtogallery/pkg/config"
+)
+
+// transientPattern matches yt-dlp failures worth retrying: rate limiting,
+// server errors and network hiccups. Anything else, such as unsupported,
+// private or deleted posts, fails on the first attempt.
+var transientPattern = regexp.MustCompile(`(?i)` +
+	`HTTP Error (?:408|425|429|5\d\d)` +
+	`|connection (?:reset|refused|aborted)` +
+	`|remote end closed connection` +
+	`|connection broken|incompleteread` +
+	`|timed out|read operation timed out` +
+	`|temporary failure in name resolution` +
+	`|network is unreachable` +
+	`|too many requests`)
+
+// retryPolicy decides how often and how quickly failed extractions are retried
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	proxies     []string
+	rotateAgent bool
+}
+
+func newRetryPolicy(cfg *config.Config) retryPolicy {
+	maxAttempts := cfg.Retry.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return retryPolicy{
+		maxAttempts: maxAttempts,
+		baseDelay:   cfg.Retry.BaseDelay,
+		maxDelay:    cfg.Retry.MaxDelay,
+		proxies:     cfg.Retry.Proxies,
+		rotateAgent: cfg.UserAgent.RotateAgents,
+	}
+}
+
+// delay returns the jittered exponential backoff before the given retry
+// (1 for the first retry): a random duration between half and all of
+// baseDelay doubled per retry, capped at maxDelay
+func (p retryPolicy) delay(retry int) time.Duration {
+	backoff := p.baseDelay << (retry - 1)
+	if backoff > p.maxDelay || backoff <= 0 {
+		backoff = p.maxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// options returns the yt-dlp options for an attempt. The first attempt runs
+// as configured; retries switch to the next user agent and proxy, if any.
+func (p retryPolicy) options(attempt int, downloader *UniversalDownloader) ExtractOptions {
+	var opts ExtractOptions
+	if attempt == 1 {
+		return opts
+	}
+
+	if p.rotateAgent {
+		opts.UserAgent = downloader.NextUserAgent()
+	}
+	if len(p.proxies) > 0 {
+		opts.Proxy = p.proxies[(attempt-2)%len(p.proxies)]
+	}
+	return opts
+}
+
+// isTransient reports whether a failed extraction may succeed if retried
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return transientPattern.MatchString(err.Error())
+}
+
+// sleepContext waits for d or until ctx ends
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
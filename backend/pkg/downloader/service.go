@@ -2,66 +2,219 @@ package downloader
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/sirupsen/logrus"
+
 	"vidtogallery/internal/models"
 	"vidtogallery/pkg/cache"
 	"vidtogallery/pkg/config"
+	"vidtogallery/pkg/quality"
 )
 
 type Downloader interface {
 	ValidateURL(url string) bool
-	ExtractVideoURL(url string) (*models.VideoResponse, error)
-	ExtractVideoURLWithQuality(url string, quality string) (*models.VideoResponse, error)
+	ExtractVideoURL(ctx context.Context, url string) (*models.VideoResponse, error)
+	ExtractVideoURLWithQuality(ctx context.Context, url string, quality string) (*models.VideoResponse, error)
 }
 
+// cacheFreshness describes whether a cached extraction can be served as-is
+type cacheFreshness int
+
+const (
+	cacheFresh cacheFreshness = iota
+	cacheStale
+	cacheExpired
+)
+
 type Service struct {
 	downloader   *UniversalDownloader
-	workers      chan struct{}
+	extractPool  *Pool
+	platforms    *platformLimiter
+	proxyPool    *Pool
+	retryAfter   time.Duration
 	mu           sync.RWMutex
 	cacheService *cache.Service
+	logger       *logrus.Logger
+	timeout      time.Duration
+	// platformTimeouts override timeout for extractions per platform
+	platformTimeouts map[string]time.Duration
+	// defaultQuality is the spec string used per platform when a request
+	// names none, validated by config.Load
+	defaultQuality map[string]string
+	freshTTL       time.Duration
+	maxStale       time.Duration
+	infoTTL        time.Duration
+	refreshing     map[string]struct{}
+	extractions    *flightGroup
+	fetches        *fetchGroup
+	proxyTimeout   time.Duration
+	retry          retryPolicy
+	lifecycle      *lifecycle
 }
 
-func NewService(maxConcurrent int, cfg *config.Config, cacheService *cache.Service) *Service {
-	return &Service{
-		downloader:   NewUniversalDownloaderWithConfig(cfg),
-		workers:      make(chan struct{}, maxConcurrent),
-		cacheService: cacheService,
+func NewService(maxConcurrent int, cfg *config.Config, cacheService *cache.Service, logger *logrus.Logger) *Service {
+	s := &Service{
+		downloader:       NewUniversalDownloaderWithConfig(cfg, logger),
+		extractPool:      NewPool(maxConcurrent, cfg.Download.ExtractQueueSize),
+		proxyPool:        NewPool(cfg.Download.MaxConcurrentProxy, cfg.Download.ProxyQueueSize),
+		platforms:        newPlatformLimiter(cfg, logger),
+		retryAfter:       cfg.Download.RetryAfter,
+		cacheService:     cacheService,
+		logger:           logger,
+		timeout:          cfg.Download.Timeout,
+		platformTimeouts: cfg.Platforms.Timeout,
+		defaultQuality:   cfg.Platforms.DefaultQuality,
+		freshTTL:         cfg.Cache.FreshTTL,
+		maxStale:         cfg.Cache.MaxStale,
+		infoTTL:          cfg.Cache.InfoTTL,
+		refreshing:       make(map[string]struct{}),
+		extractions:      newFlightGroup(),
+		fetches:          newFetchGroup(cfg.Download.SpoolDir),
+		proxyTimeout:     cfg.Download.ProxyTimeout,
+		retry:            newRetryPolicy(cfg),
+		lifecycle:        newLifecycle(),
 	}
+
+	s.downloader.workers = newWorkerPool(cfg, logger)
+	s.downloader.binaries = newBinaryManager(cfg, logger, s.downloader.workers.reload)
+
+	if err := s.downloader.qualityManager.ConfigureProfiles(cfg.Quality.Profiles); err != nil {
+		logger.WithError(err).Warn("Ignoring invalid quality profiles")
+	}
+	return s
+}
+
+// ResolveQuality turns a quality profile name or spec string into a spec.
+// Without one, the default quality of url's platform applies. Invalid specs
+// fail with quality.ErrInvalidSpec.
+func (s *Service) ResolveQuality(url, qualitySpec string) (quality.Spec, error) {
+	if strings.TrimSpace(qualitySpec) == "" {
+		qualitySpec = s.defaultQuality[s.downloader.DetectPlatform(url)]
+	}
+	return s.downloader.qualityManager.Resolve(qualitySpec)
+}
+
+// Timeout is how long an extraction of url may take
+func (s *Service) Timeout(url string) time.Duration {
+	if timeout, ok := s.platformTimeouts[s.downloader.DetectPlatform(url)]; ok && timeout > 0 {
+		return timeout
+	}
+	return s.timeout
+}
+
+// AutoQuality chooses a spec from the client hints of a request and
+// explains the choice
+func (s *Service) AutoQuality(hints quality.Hints) (quality.Spec, string) {
+	return s.downloader.qualityManager.AutoSpec(hints)
+}
+
+// WithQualityReason returns a copy of video recording why the auto quality
+// chose its spec. The video may be shared with other requests, so it is not
+// modified.
+func WithQualityReason(video *models.VideoResponse, reason string) *models.VideoResponse {
+	annotated := *video
+	annotated.Metadata = maps.Clone(video.Metadata)
+	if annotated.Metadata == nil {
+		annotated.Metadata = make(map[string]string)
+	}
+	annotated.Metadata["quality_mode"] = quality.Auto
+	annotated.Metadata["quality_reason"] = reason
+	return &annotated
+}
+
+// QualityProfiles returns the named quality profiles clients can request
+func (s *Service) QualityProfiles() []quality.Profile {
+	return s.downloader.qualityManager.Profiles()
 }
 
 func (s *Service) DetectPlatform(url string) string {
 	return s.downloader.DetectPlatform(url)
 }
 
+func (s *Service) DetectMediaPlatform(videoURL string) string {
+	return s.downloader.DetectMediaPlatform(videoURL)
+}
+
 func (s *Service) ProcessURL(ctx context.Context, url string) (*models.VideoResponse, error) {
 	return s.ProcessURLWithQuality(ctx, url, "best")
 }
 
-func (s *Service) ProcessURLWithQuality(ctx context.Context, url string, quality string) (*models.VideoResponse, error) {
+// ProcessURLWithQuality extracts url with a quality profile or spec as
+// resolved by ResolveQuality
+func (s *Service) ProcessURLWithQuality(ctx context.Context, url string, qualitySpec string) (*models.VideoResponse, error) {
+	spec, err := s.ResolveQuality(url, qualitySpec)
+	if err != nil {
+		return nil, err
+	}
+
 	// Try to get from cache first
-	cacheKey := fmt.Sprintf("%s:%s", url, quality)
+	cacheKey := fmt.Sprintf("%s:%s", CanonicalURL(url), spec)
 	if cachedVideo, found := s.cacheService.GetVideo(ctx, cacheKey); found {
-		return cachedVideo, nil
+		switch s.freshness(cachedVideo) {
+		case cacheFresh:
+			return cachedVideo, nil
+		case cacheStale:
+			s.revalidate(url, spec, cacheKey)
+			return cachedVideo, nil
+		}
+		// Too old or its video URL is about to expire: extract synchronously
 	}
 
-	// Acquire worker slot
-	select {
-	case s.workers <- struct{}{}:
-		defer func() { <-s.workers }()
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	// Concurrent requests for the same post and quality share one extraction
+	video, shared, err := s.extractions.do(ctx, cacheKey, func(ctx context.Context) (*models.VideoResponse, error) {
+		return s.extract(ctx, url, spec, cacheKey)
+	})
+	if shared {
+		s.logger.WithField("key", cacheKey).Debug("Joined in-flight extraction")
+	}
+	return video, err
+}
+
+// extract picks the format for spec from the cached yt-dlp info of the post,
+// or from a fresh one fetched by fetchInfo, and caches the result
+func (s *Service) extract(ctx context.Context, url string, spec quality.Spec, cacheKey string) (*models.VideoResponse, error) {
+	ctx, done, err := s.lifecycle.begin(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer done()
+
+	if info, found := s.cachedInfo(ctx, url); found {
+		video, err := s.downloader.VideoFromInfo(url, info, spec)
+		if err != nil {
+			return nil, err
+		}
+		video.Metadata["info_cached"] = "true"
+		if err := s.cacheService.SetVideo(ctx, cacheKey, video); err != nil {
+			// Log error but don't fail the request
+		}
+		return video, nil
+	}
+
+	info, attempt, err := s.fetchInfo(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cacheInfo(ctx, url, info)
 
-	// Use universal downloader
-	video, err := s.downloader.ExtractVideoURLWithQuality(url, quality)
+	video, err := s.downloader.VideoFromInfo(url, info, spec)
 	if err != nil {
 		return nil, err
 	}
+	video.Metadata["attempts"] = strconv.Itoa(attempt)
 
 	// Cache the result with quality-specific key
 	if err := s.cacheService.SetVideo(ctx, cacheKey, video); err != nil {
@@ -71,50 +224,392 @@ func (s *Service) ProcessURLWithQuality(ctx context.Context, url string, quality
 	return video, nil
 }
 
+// fetchInfo runs yt-dlp for url on a pool worker within the platform's
+// extraction timeout, retrying transient failures. The worker is released
+// while waiting between attempts. It returns the number of attempts made.
+func (s *Service) fetchInfo(ctx context.Context, url string) (*UniversalYtDlpInfo, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout(url))
+	defer cancel()
+
+	platform := s.downloader.DetectPlatform(url)
+
+	var info *UniversalYtDlpInfo
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		// Wait for the platform's own limits before taking a worker, so a
+		// throttled platform does not hold workers other platforms could use
+		release, limitErr := s.platforms.acquire(ctx, platform)
+		if limitErr != nil {
+			return nil, attempt, limitErr
+		}
+
+		opts := s.retry.options(attempt, s.downloader)
+		poolErr := s.extractPool.Run(ctx, func() {
+			reportProgress(ctx, models.Progress{Stage: models.StageExtracting})
+
+			// Use universal downloader
+			info, err = s.downloader.FetchInfo(ctx, url, opts)
+		})
+		if poolErr != nil {
+			release(nil)
+			return nil, attempt, poolErr
+		}
+		release(err)
+
+		if err == nil || attempt >= s.retry.maxAttempts || !isTransient(err) {
+			break
+		}
+
+		delay := s.retry.delay(attempt)
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"url":      url,
+			"attempt":  attempt,
+			"retry_in": delay,
+		}).Warn("Transient extraction failure, retrying")
+		reportProgress(ctx, models.Progress{
+			Stage:   models.StageExtracting,
+			Message: fmt.Sprintf("Retrying in %s (attempt %d of %d failed)", delay.Round(time.Millisecond), attempt, s.retry.maxAttempts),
+		})
+
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			break
+		}
+	}
+	if err != nil {
+		if attempt > 1 {
+			return nil, attempt, fmt.Errorf("extraction failed after %d attempts: %w", attempt, err)
+		}
+		return nil, attempt, err
+	}
+	return info, attempt, nil
+}
+
+// freshness classifies a cached extraction by its age and by the expiry of its signed video URL
+func (s *Service) freshness(video *models.VideoResponse) cacheFreshness {
+	now := time.Now()
+	age := now.Sub(video.ProcessedAt)
+
+	if age >= s.freshTTL+s.maxStale {
+		return cacheExpired
+	}
+
+	if expiresAt, ok := videoURLExpiry(video.VideoURL); ok {
+		remaining := expiresAt.Sub(now)
+		if remaining <= urlExpiryMargin {
+			return cacheExpired
+		}
+		// Still usable, but refresh before the signed URL runs out
+		if remaining <= s.freshTTL {
+			return cacheStale
+		}
+	}
+
+	if age < s.freshTTL {
+		return cacheFresh
+	}
+	return cacheStale
+}
+
+// revalidate refreshes a stale cache entry in the background. At most one refresh
+// runs per key, and refreshes only use a worker slot when one is free so they never
+// queue ahead of foreground requests.
+func (s *Service) revalidate(url string, spec quality.Spec, cacheKey string) {
+	s.mu.Lock()
+	if _, inFlight := s.refreshing[cacheKey]; inFlight {
+		s.mu.Unlock()
+		return
+	}
+	s.refreshing[cacheKey] = struct{}{}
+	s.mu.Unlock()
+
+	done := func() {
+		s.mu.Lock()
+		delete(s.refreshing, cacheKey)
+		s.mu.Unlock()
+	}
+
+	platform := s.downloader.DetectPlatform(url)
+	release, ok := s.platforms.tryAcquire(platform)
+	if !ok {
+		done()
+		s.logger.WithField("key", cacheKey).Debug("Platform limit reached, skipping background revalidation")
+		return
+	}
+
+	var extractErr error
+	started := s.extractPool.TryGo(func() {
+		defer done()
+		defer func() { release(extractErr) }()
+
+		ctx, finished, err := s.lifecycle.begin(context.Background())
+		if err != nil {
+			return
+		}
+		defer finished()
+
+		ctx, cancel := context.WithTimeout(ctx, s.Timeout(url))
+		defer cancel()
+
+		info, err := s.downloader.FetchInfo(ctx, url, ExtractOptions{})
+		extractErr = err
+		if err != nil {
+			s.logger.WithError(err).WithField("key", cacheKey).Warn("Background revalidation failed")
+			return
+		}
+		s.cacheInfo(ctx, url, info)
+
+		video, err := s.downloader.VideoFromInfo(url, info, spec)
+		if err != nil {
+			s.logger.WithError(err).WithField("key", cacheKey).Warn("Background revalidation failed")
+			return
+		}
+
+		if err := s.cacheService.SetVideo(ctx, cacheKey, video); err != nil {
+			s.logger.WithError(err).WithField("key", cacheKey).Warn("Failed to store revalidated video")
+			return
+		}
+
+		s.logger.WithField("key", cacheKey).Debug("Cache entry revalidated")
+	})
+	if !started {
+		release(nil)
+		done()
+		s.logger.WithField("key", cacheKey).Debug("Workers busy, skipping background revalidation")
+	}
+}
+
+// GetAvailableQualities lists the qualities of the post at url. The yt-dlp
+// info is cached, so a following extraction in any of them needs no second run.
 func (s *Service) GetAvailableQualities(ctx context.Context, url string) (*models.QualitiesResponse, error) {
-	return s.downloader.GetAvailableQualities(url)
+	if info, found := s.cachedInfo(ctx, url); found {
+		return s.downloader.QualitiesFromInfo(url, info), nil
+	}
+
+	ctx, done, err := s.lifecycle.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	info, _, err := s.fetchInfo(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get available qualities: %w", err)
+	}
+
+	s.cacheInfo(ctx, url, info)
+	return s.downloader.QualitiesFromInfo(url, info), nil
+}
+
+// PoolStats reports worker usage and queue depth of the extraction and proxy download pools
+func (s *Service) PoolStats() map[string]models.PoolStats {
+	return map[string]models.PoolStats{
+		"extract": s.extractPool.Stats(),
+		"proxy":   s.proxyPool.Stats(),
+	}
+}
+
+// YtDlpStatus reports the active yt-dlp binary and the version the workers import
+func (s *Service) YtDlpStatus() models.YtDlpStatus {
+	status := s.downloader.binaries.status()
+	status.WorkerVersion = s.downloader.workers.version()
+	return status
+}
+
+// RetryAfter is how long clients turned away with ErrQueueFull should wait before retrying
+func (s *Service) RetryAfter() time.Duration {
+	return s.retryAfter
 }
 
-// ProxyDownload downloads video through backend to avoid CORS issues
-func (s *Service) ProxyDownload(ctx context.Context, videoURL string) (*models.ProxyDownloadResponse, error) {
-	s.workers <- struct{}{}
-	defer func() { <-s.workers }()
+// ProxyDownload downloads video through backend to avoid CORS issues.
+// Concurrent requests for the same video URL share one upstream download.
+// When the source post is known, a file cached for it is served even if the
+// CDN URL has changed since.
+func (s *Service) ProxyDownload(ctx context.Context, req *models.ProxyDownloadRequest) (*models.ProxyDownloadResponse, error) {
+	sourceKey := s.sourceKey(ctx, req)
 
 	// Check cache first
 	if s.cacheService != nil {
-		if cachedData, err := s.cacheService.GetVideoFile(ctx, videoURL); err == nil && cachedData != nil {
-			return &models.ProxyDownloadResponse{
-				Data: cachedData,
-			}, nil
+		if response, ok := s.cachedFile(ctx, req.VideoURL, sourceKey); ok {
+			return response, nil
 		}
 	}
 
+	reader, err := s.fetches.join(ctx, req.VideoURL, func(ctx context.Context, fetch *sharedFetch, spool *os.File) {
+		s.fetchUpstream(ctx, req.VideoURL, sourceKey, fetch, spool)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start video download: %w", err)
+	}
+
+	size, err := reader.wait(ctx)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+
+	return &models.ProxyDownloadResponse{
+		Body: reader,
+		Size: size,
+	}, nil
+}
+
+// sourceKey returns the key indexing the file of req by source post and
+// quality. The client names the source, so the key is only used when
+// video_url is the one our own cached extraction of that post returned;
+// otherwise anyone could map a post to an arbitrary file.
+func (s *Service) sourceKey(ctx context.Context, req *models.ProxyDownloadRequest) string {
+	if req.SourceURL == "" || s.cacheService == nil {
+		return ""
+	}
+	spec, err := s.ResolveQuality(req.SourceURL, req.Quality)
+	if err != nil {
+		return ""
+	}
+
+	key := fmt.Sprintf("%s:%s", CanonicalURL(req.SourceURL), spec)
+	video, found := s.cacheService.GetVideo(ctx, key)
+	if !found || video.VideoURL != req.VideoURL {
+		return ""
+	}
+	return key
+}
+
+// cachedFile looks up a cached file by video URL, then by source post and quality
+func (s *Service) cachedFile(ctx context.Context, videoURL string, sourceKey string) (*models.ProxyDownloadResponse, bool) {
+	file, entry, err := s.cacheService.GetVideoFile(ctx, videoURL)
+	if err != nil && sourceKey != "" {
+		file, entry, err = s.cacheService.GetSourceFile(ctx, sourceKey)
+	}
+	if err != nil {
+		return nil, false
+	}
+
+	return &models.ProxyDownloadResponse{
+		Body:        file,
+		Size:        entry.Size,
+		ContentHash: entry.Hash,
+	}, true
+}
+
+// DownloadFile fetches a video file into the file cache, reporting byte
+// progress, and returns its cache record
+func (s *Service) DownloadFile(ctx context.Context, req *models.ProxyDownloadRequest) (*models.VideoFile, error) {
+	response, err := s.ProxyDownload(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	body := TrackProgress(response.Body, response.Size, func(progress models.Progress) {
+		reportProgress(ctx, progress)
+	})
+	defer body.Close()
+
+	buf := make([]byte, 32*1024)
+	var size int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		n, readErr := body.Read(buf)
+		size += int64(n)
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+
+	if response.ContentHash != "" {
+		return &models.VideoFile{Hash: response.ContentHash, Size: response.Size}, nil
+	}
+
+	// Freshly fetched files are indexed before readers see the end of the stream
+	if s.cacheService != nil {
+		if file, entry, err := s.cacheService.GetVideoFile(ctx, req.VideoURL); err == nil {
+			file.Close()
+			return entry, nil
+		}
+	}
+	return &models.VideoFile{Size: size}, nil
+}
+
+// fetchUpstream downloads videoURL on a proxy pool worker into the spool file shared by all readers of fetch
+func (s *Service) fetchUpstream(ctx context.Context, videoURL string, sourceKey string, fetch *sharedFetch, spool *os.File) {
+	defer spool.Close()
+
+	ctx, done, err := s.lifecycle.begin(ctx)
+	if err != nil {
+		fetch.finish(err)
+		return
+	}
+	defer done()
+
+	ctx, cancel := context.WithTimeout(ctx, s.proxyTimeout)
+	defer cancel()
+
+	if err := s.proxyPool.Run(ctx, func() {
+		s.fetchToSpool(ctx, videoURL, sourceKey, fetch, spool)
+	}); err != nil {
+		fetch.finish(err)
+	}
+}
+
+func (s *Service) fetchToSpool(ctx context.Context, videoURL string, sourceKey string, fetch *sharedFetch, spool *os.File) {
+
 	// Download video file
-	response, err := http.Get(videoURL)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, videoURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download video: %w", err)
+		fetch.finish(fmt.Errorf("failed to download video: %w", err))
+		return
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		fetch.finish(fmt.Errorf("failed to download video: %w", err))
+		return
 	}
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to download video: HTTP %d", response.StatusCode)
+		fetch.finish(fmt.Errorf("failed to download video: HTTP %d", response.StatusCode))
+		return
 	}
 
-	// Read video data
-	data, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read video data: %w", err)
+	fetch.begin(response.ContentLength)
+
+	// Hash while spooling so the file can be stored by content
+	hash := sha256.New()
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := response.Body.Read(buf)
+		if n > 0 {
+			if _, err := spool.Write(buf[:n]); err != nil {
+				fetch.finish(fmt.Errorf("failed to spool video data: %w", err))
+				return
+			}
+			hash.Write(buf[:n])
+			fetch.advance(n)
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			fetch.finish(fmt.Errorf("failed to read video data: %w", readErr))
+			return
+		}
 	}
 
-	// Cache the video file with 5 minute expiration
+	// Cache before releasing readers: the spool file is removed once the last one is done
 	if s.cacheService != nil {
-		if err := s.cacheService.CacheVideoFile(ctx, videoURL, data, 5*time.Minute); err != nil {
+		contentHash := hex.EncodeToString(hash.Sum(nil))
+		if err := s.cacheService.CacheVideoFile(ctx, videoURL, sourceKey, spool.Name(), contentHash); err != nil && !errors.Is(err, cache.ErrCacheDisabled) {
 			// Log error but don't fail the request
-			fmt.Printf("Failed to cache video file: %v\n", err)
+			s.logger.WithError(err).WithField("video_url", videoURL).Warn("Failed to cache video file")
 		}
 	}
 
-	return &models.ProxyDownloadResponse{
-		Data: data,
-	}, nil
+	fetch.finish(nil)
 }
@@ -1,13 +1,17 @@
 package downloader
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/sirupsen/logrus"
+
 	"vidtogallery/internal/models"
 	"vidtogallery/pkg/config"
 	"vidtogallery/pkg/quality"
@@ -16,9 +20,16 @@ import (
 
 // UniversalDownloader handles video extraction from any platform supported by yt-dlp
 type UniversalDownloader struct {
+	logger         *logrus.Logger
 	uaRotator      *useragent.Rotator
 	qualityManager *quality.Manager
 	ytdlpPath      string
+	// binaries replaces ytdlpPath with newer binaries at runtime, nil when unmanaged
+	binaries *binaryManager
+	// workers run extractions in long-lived yt-dlp processes, nil when disabled
+	workers *workerPool
+	// platformArgs are the configured extra yt-dlp arguments per platform
+	platformArgs map[string][]string
 }
 
 // UniversalYtDlpInfo represents the JSON structure returned by yt-dlp
@@ -28,17 +39,57 @@ type UniversalYtDlpInfo struct {
 	Description string                 `json:"description"`
 	Duration    float64                `json:"duration"`
 	Thumbnail   string                 `json:"thumbnail"`
+	Extractor   string                 `json:"extractor"`
 	Formats     []UniversalYtDlpFormat `json:"formats,omitempty"`
 }
 
 type UniversalYtDlpFormat struct {
-	FormatID string `json:"format_id"`
-	URL      string `json:"url"`
-	Height   int    `json:"height"`
-	Width    int    `json:"width"`
-	VCodec   string `json:"vcodec"`
-	ACodec   string `json:"acodec"`
-	FileSize int64  `json:"filesize,omitempty"`
+	FormatID     string  `json:"format_id"`
+	URL          string  `json:"url"`
+	Height       int     `json:"height"`
+	Width        int     `json:"width"`
+	FPS          float64 `json:"fps,omitempty"`
+	VCodec       string  `json:"vcodec"`
+	ACodec       string  `json:"acodec"`
+	Ext          string  `json:"ext,omitempty"`
+	FileSize     int64   `json:"filesize,omitempty"`
+	FileSizeEst  int64   `json:"filesize_approx,omitempty"`
+	TBR          float64 `json:"tbr,omitempty"`
+	DynamicRange string  `json:"dynamic_range,omitempty"`
+}
+
+// qualityFormat converts a yt-dlp format for matching against a quality spec
+func (f UniversalYtDlpFormat) qualityFormat() quality.Format {
+	return quality.Format{
+		ID:           f.FormatID,
+		URL:          f.URL,
+		Width:        f.Width,
+		Height:       f.Height,
+		FPS:          f.FPS,
+		VCodec:       f.VCodec,
+		ACodec:       f.ACodec,
+		Ext:          f.Ext,
+		FileSize:     f.size(),
+		Bitrate:      f.TBR,
+		DynamicRange: f.DynamicRange,
+	}
+}
+
+// size returns the exact file size, or yt-dlp's estimate when unknown
+func (f UniversalYtDlpFormat) size() int64 {
+	if f.FileSize > 0 {
+		return f.FileSize
+	}
+	return f.FileSizeEst
+}
+
+// qualityFormats converts yt-dlp formats for ranking by the quality manager
+func qualityFormats(formats []UniversalYtDlpFormat) []quality.Format {
+	converted := make([]quality.Format, len(formats))
+	for i, format := range formats {
+		converted[i] = format.qualityFormat()
+	}
+	return converted
 }
 
 // Supported platforms with their regex patterns
@@ -48,25 +99,63 @@ var platformPatterns = map[string]*regexp.Regexp{
 	"tiktok":    regexp.MustCompile(`^(?:https?://)?(?:www\.)?(?:tiktok\.com|vm\.tiktok\.com)`),
 }
 
+// CDN hosts serving media for each platform, used to attribute direct video URLs
+var mediaHostPatterns = map[string]*regexp.Regexp{
+	"instagram": regexp.MustCompile(`^(?:https?://)?[^/]*(?:cdninstagram\.com|fbcdn\.net)(?:[:/]|$)`),
+	"twitter":   regexp.MustCompile(`^(?:https?://)?[^/]*twimg\.com(?:[:/]|$)`),
+	"tiktok":    regexp.MustCompile(`^(?:https?://)?[^/]*(?:tiktokcdn(?:-[a-z]+)?\.com|tiktokv\.com|tiktok\.com|byteoversea\.com|ibyteimg\.com)(?:[:/]|$)`),
+}
+
 // YouTube pattern for detecting and rejecting YouTube URLs
 var youtubePattern = regexp.MustCompile(`^(?:https?://)?(?:www\.)?(?:youtube\.com/watch\?v=|youtu\.be/|youtube\.com/shorts/)([A-Za-z0-9_-]+)`)
 
 func NewUniversalDownloader() *UniversalDownloader {
 	return &UniversalDownloader{
+		logger:         logrus.StandardLogger(),
 		uaRotator:      useragent.NewRotator(true),
 		qualityManager: quality.NewManager(),
 		ytdlpPath:      "yt-dlp",
 	}
 }
 
-func NewUniversalDownloaderWithConfig(cfg *config.Config) *UniversalDownloader {
+func NewUniversalDownloaderWithConfig(cfg *config.Config, logger *logrus.Logger) *UniversalDownloader {
 	return &UniversalDownloader{
+		logger:         logger,
 		uaRotator:      useragent.NewRotator(cfg.UserAgent.RandomOrder),
 		qualityManager: quality.NewManager(),
-		ytdlpPath:      "yt-dlp",
+		ytdlpPath:      cfg.YtDlp.Path,
+		platformArgs:   platformArgs(cfg),
 	}
 }
 
+// platformArgs combines the configured yt-dlp and extractor arguments of
+// each platform, which config.Load has already validated
+func platformArgs(cfg *config.Config) map[string][]string {
+	args := make(map[string][]string)
+	for platform, extra := range cfg.Platforms.YtDlpArgs {
+		args[platform] = append(args[platform], extra...)
+	}
+	for platform, values := range cfg.Platforms.ExtractorArgs {
+		for _, value := range values {
+			args[platform] = append(args[platform], "--extractor-args", value)
+		}
+	}
+	return args
+}
+
+// binary returns the yt-dlp executable to run
+func (d *UniversalDownloader) binary() ytdlpBinary {
+	if d.binaries == nil {
+		return ytdlpBinary{path: d.ytdlpPath}
+	}
+	return d.binaries.current()
+}
+
+// NextUserAgent returns the next user agent from the rotation
+func (d *UniversalDownloader) NextUserAgent() string {
+	return d.uaRotator.Next()
+}
+
 func (d *UniversalDownloader) ValidateURL(url string) bool {
 	// Clean the URL by trimming whitespace
 	url = strings.TrimSpace(url)
@@ -96,13 +185,50 @@ func (d *UniversalDownloader) DetectPlatform(url string) string {
 	return "unknown"
 }
 
+// DetectMediaPlatform attributes a direct CDN video URL to the platform serving it
+func (d *UniversalDownloader) DetectMediaPlatform(videoURL string) string {
+	videoURL = strings.TrimSpace(videoURL)
+
+	for platform, pattern := range mediaHostPatterns {
+		if pattern.MatchString(videoURL) {
+			return platform
+		}
+	}
+	return "unknown"
+}
+
 // ExtractVideoURL extracts video URL with default "best" quality
-func (d *UniversalDownloader) ExtractVideoURL(url string) (*models.VideoResponse, error) {
-	return d.ExtractVideoURLWithQuality(url, "best")
+func (d *UniversalDownloader) ExtractVideoURL(ctx context.Context, url string) (*models.VideoResponse, error) {
+	return d.ExtractVideoURLWithQuality(ctx, url, "best")
+}
+
+// ExtractOptions adjust how a single yt-dlp run reaches the platform
+type ExtractOptions struct {
+	UserAgent string
+	Proxy     string
 }
 
 // ExtractVideoURLWithQuality extracts video URL using yt-dlp
-func (d *UniversalDownloader) ExtractVideoURLWithQuality(url string, quality string) (*models.VideoResponse, error) {
+func (d *UniversalDownloader) ExtractVideoURLWithQuality(ctx context.Context, url string, qualitySpec string) (*models.VideoResponse, error) {
+	spec, err := d.qualityManager.Resolve(qualitySpec)
+	if err != nil {
+		return nil, err
+	}
+	return d.ExtractWithOptions(ctx, url, spec, ExtractOptions{})
+}
+
+// ExtractWithOptions extracts video URL using yt-dlp with a specific user agent or proxy
+func (d *UniversalDownloader) ExtractWithOptions(ctx context.Context, url string, spec quality.Spec, opts ExtractOptions) (*models.VideoResponse, error) {
+	info, err := d.FetchInfo(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	return d.VideoFromInfo(url, info, spec)
+}
+
+// FetchInfo runs yt-dlp once for url without selecting a format, so the
+// info lists every format and any quality can be picked from it afterwards
+func (d *UniversalDownloader) FetchInfo(ctx context.Context, url string, opts ExtractOptions) (*UniversalYtDlpInfo, error) {
 	// Clean the URL by trimming whitespace
 	url = strings.TrimSpace(url)
 
@@ -115,55 +241,45 @@ func (d *UniversalDownloader) ExtractVideoURLWithQuality(url string, quality str
 		return nil, fmt.Errorf("unsupported URL or platform")
 	}
 
-	// Check if yt-dlp is available
-	if _, err := exec.LookPath(d.ytdlpPath); err != nil {
-		return nil, fmt.Errorf("yt-dlp not found in PATH: %w", err)
-	}
-
 	// Prepare yt-dlp command
 	args := []string{
 		"--no-check-certificate",
 		"--no-warnings",
-		"--dump-json",
 		"--no-playlist",
 	}
+	args = append(args, d.platformArgs[d.DetectPlatform(url)]...)
 
-	// Add quality selection based on preference
-	switch quality {
-	case "best":
-		args = append(args, "--format", "bestvideo[ext=mp4]+bestaudio/best")
-	case "worst":
-		args = append(args, "--format", "worstvideo[ext=mp4]+worstaudio/worst")
-	default:
-		// For specific quality formats like "best[height<=1080]", use proper yt-dlp syntax
-		if quality != "" && strings.Contains(quality, "height<=") {
-			// Extract height value and use proper format selection
-			args = append(args, "--format", "bestvideo"+quality[4:]+"+bestaudio/best")
-		} else if quality != "" {
-			args = append(args, "--format", quality)
-		} else {
-			// Default to best quality if no quality specified
-			args = append(args, "--format", "bestvideo[ext=mp4]+bestaudio/best")
-		}
+	if opts.UserAgent != "" {
+		args = append(args, "--user-agent", opts.UserAgent)
+	}
+	if opts.Proxy != "" {
+		args = append(args, "--proxy", opts.Proxy)
 	}
 
 	args = append(args, url)
 
-	// Execute yt-dlp
-	cmd := exec.Command(d.ytdlpPath, args...)
+	// Prefer a long-lived worker, falling back to running the binary
+	output, err := d.workers.run(ctx, args)
+	if errors.Is(err, errWorkersUnavailable) {
+		binary := d.binary()
+		if binary.err != nil {
+			return nil, binary.err
+		}
+
+		// Check if yt-dlp is available
+		if _, err := exec.LookPath(binary.path); err != nil {
+			return nil, fmt.Errorf("yt-dlp not found in PATH: %w", err)
+		}
 
-	// Log the command being executed for debugging
-	fmt.Printf("DEBUG: Executing yt-dlp with args: %v\n", args)
+		args = append([]string{"--dump-json"}, args...)
 
-	output, err := cmd.Output()
+		d.logger.WithField("args", args).Debug("Executing yt-dlp")
+
+		// Execute yt-dlp
+		output, err = runYtDlp(ctx, binary.path, args)
+	}
 	if err != nil {
-		// Get stderr for better error reporting
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			stderr := string(exitErr.Stderr)
-			fmt.Printf("DEBUG: yt-dlp stderr: %s\n", stderr)
-			return nil, fmt.Errorf("yt-dlp failed: %w, stderr: %s", err, stderr)
-		}
-		return nil, fmt.Errorf("yt-dlp failed: %w", err)
+		return nil, err
 	}
 
 	// Parse JSON output
@@ -171,84 +287,36 @@ func (d *UniversalDownloader) ExtractVideoURLWithQuality(url string, quality str
 	if err := json.Unmarshal(output, &info); err != nil {
 		return nil, fmt.Errorf("failed to parse yt-dlp output: %w", err)
 	}
+	return &info, nil
+}
+
+// VideoFromInfo picks the format best matching spec from info
+func (d *UniversalDownloader) VideoFromInfo(url string, info *UniversalYtDlpInfo, spec quality.Spec) (*models.VideoResponse, error) {
+	url = strings.TrimSpace(url)
 
 	// Get the video URL - always check formats first for quality selection
 	videoURL := ""
 
 	// Try to find the best format matching the requested quality
 	if len(info.Formats) > 0 {
-		var selectedFormat *UniversalYtDlpFormat
-
-		// Parse the quality parameter to understand what we're looking for
-		switch {
-		case quality == "best":
-			// Find format with highest resolution
-			maxHeight := 0
-			for _, format := range info.Formats {
-				if format.URL != "" && format.VCodec != "none" {
-					if format.Height > maxHeight {
-						maxHeight = format.Height
-						selectedFormat = &format
-					} else if selectedFormat == nil {
-						selectedFormat = &format
-					}
-				}
-			}
-		case quality == "worst":
-			// Find format with lowest resolution
-			minHeight := 99999
-			for _, format := range info.Formats {
-				if format.URL != "" && format.VCodec != "none" {
-					if format.Height > 0 && format.Height < minHeight {
-						minHeight = format.Height
-						selectedFormat = &format
-					} else if selectedFormat == nil {
-						selectedFormat = &format
-					}
-				}
-			}
-		case strings.Contains(quality, "height<="):
-			// Extract target height from quality string like "best[height<=720]"
-			var targetHeight int
-			if _, err := fmt.Sscanf(quality, "best[height<=%d]", &targetHeight); err == nil {
-				// Find best format that doesn't exceed target height
-				bestHeight := 0
-				for _, format := range info.Formats {
-					if format.URL != "" && format.VCodec != "none" && format.Height <= targetHeight {
-						if format.Height > bestHeight {
-							bestHeight = format.Height
-							selectedFormat = &format
-						} else if selectedFormat == nil {
-							selectedFormat = &format
-						}
-					}
-				}
-			}
-		default:
-			// For other qualities, try to find the best available
-			maxHeight := 0
-			for _, format := range info.Formats {
-				if format.URL != "" && format.VCodec != "none" {
-					if format.Height > maxHeight {
-						maxHeight = format.Height
-						selectedFormat = &format
-					} else if selectedFormat == nil {
-						selectedFormat = &format
-					}
-				}
-			}
-		}
-
-		if selectedFormat != nil {
+		if selectedFormat := d.qualityManager.SelectFormat(qualityFormats(info.Formats), spec); selectedFormat != nil {
 			videoURL = selectedFormat.URL
-			fmt.Printf("DEBUG: Selected format with height %d for quality '%s': %s\n", selectedFormat.Height, quality, videoURL)
+			d.logger.WithFields(logrus.Fields{
+				"format_id": selectedFormat.ID,
+				"height":    selectedFormat.Height,
+				"quality":   spec.String(),
+			}).Debug("Selected format")
 		}
 	}
 
-	// Fallback to info.URL if no format was selected
+	// Fallback to info.URL for extractors that do not list formats. With a
+	// format list, no match means the spec's limits rule them all out.
 	if videoURL == "" {
+		if len(info.Formats) > 0 {
+			return nil, fmt.Errorf("no format matches quality %s", spec)
+		}
 		videoURL = info.URL
-		fmt.Printf("DEBUG: Using fallback URL: %s\n", videoURL)
+		d.logger.WithField("url", url).Debug("No format list, using the info URL")
 	}
 
 	// Validate that we got a video URL
@@ -263,56 +331,56 @@ func (d *UniversalDownloader) ExtractVideoURLWithQuality(url string, quality str
 		VideoURL:    videoURL,
 		Title:       info.Title,
 		Platform:    platform,
-		Quality:     quality,
+		Quality:     spec.String(),
 		ProcessedAt: time.Now(),
 		Metadata: map[string]string{
 			"source":      url,
 			"description": info.Description,
 			"duration":    fmt.Sprintf("%.1f", info.Duration),
 			"thumbnail":   info.Thumbnail,
+			"extractor":   info.Extractor,
 		},
 	}, nil
 }
 
-func (d *UniversalDownloader) GetAvailableQualities(url string) (*models.QualitiesResponse, error) {
-	// Clean the URL by trimming whitespace
-	url = strings.TrimSpace(url)
-
-	// Check if it's a YouTube URL and return specific error
-	if youtubePattern.MatchString(url) {
-		return nil, fmt.Errorf("YouTube videos are not supported at this time")
-	}
-
-	if !d.ValidateURL(url) {
-		return nil, fmt.Errorf("unsupported URL or platform")
-	}
-
-	// Check if yt-dlp is available
-	if _, err := exec.LookPath(d.ytdlpPath); err != nil {
-		return nil, fmt.Errorf("yt-dlp not found in PATH: %w", err)
+// qualityOption describes the format a quality spec resolves to. Without a
+// known file size, the size is estimated from the bitrate and duration.
+func qualityOption(format quality.Format, spec quality.Spec, label string, duration float64) models.QualityOption {
+	size := format.FileSize
+	if size == 0 && format.Bitrate > 0 && duration > 0 {
+		size = int64(format.Bitrate * 1000 / 8 * duration)
 	}
 
-	// Get available formats using yt-dlp with --list-formats and --dump-json
-	args := []string{
-		"--dump-json",
-		"--no-warnings",
-		"--no-check-certificate",
-		"--no-playlist",
-		url,
+	return models.QualityOption{
+		Quality:     spec.String(),
+		Label:       label,
+		Width:       format.Width,
+		Height:      format.Height,
+		Orientation: quality.Orientation(format.Width, format.Height),
+		AspectRatio: quality.AspectRatio(format.Width, format.Height),
+		FPS:         format.FPS,
+		VCodec:      format.VCodec,
+		ACodec:      format.ACodec,
+		Container:   format.Ext,
+		Bitrate:     format.Bitrate,
+		FileSize:    size,
+		HDR:         format.HDR(),
+		HasAudio:    format.HasAudio(),
+		VideoURL:    format.URL,
 	}
+}
 
-	fmt.Printf("DEBUG: Getting qualities with args: %v\n", args)
-
-	cmd := exec.Command(d.ytdlpPath, args...)
-	output, err := cmd.Output()
+func (d *UniversalDownloader) GetAvailableQualities(ctx context.Context, url string) (*models.QualitiesResponse, error) {
+	info, err := d.FetchInfo(ctx, url, ExtractOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get available qualities: %w", err)
 	}
+	return d.QualitiesFromInfo(url, info), nil
+}
 
-	var info UniversalYtDlpInfo
-	if err := json.Unmarshal(output, &info); err != nil {
-		return nil, fmt.Errorf("failed to parse yt-dlp JSON: %w", err)
-	}
+// QualitiesFromInfo lists the qualities offered by the formats of info
+func (d *UniversalDownloader) QualitiesFromInfo(url string, info *UniversalYtDlpInfo) *models.QualitiesResponse {
+	url = strings.TrimSpace(url)
 
 	// Platform detection
 	platform := d.DetectPlatform(url)
@@ -328,46 +396,41 @@ func (d *UniversalDownloader) GetAvailableQualities(url string) (*models.Qualiti
 
 	// Parse specific formats if available
 	if len(info.Formats) > 0 {
+		var options []models.QualityOption
 		seenQualities := make(map[string]bool)
 
-		// Sort formats by height descending to get better quality first
-		for _, format := range info.Formats {
-			if format.Height > 0 && format.VCodec != "none" {
-				qualityLabel := fmt.Sprintf("%dp", format.Height)
-				qualityId := fmt.Sprintf("best[height<=%d]", format.Height)
-
-				if !seenQualities[qualityLabel] {
-					qualities = append(qualities, models.QualityOption{
-						Quality: qualityId,
-						Label:   qualityLabel,
-					})
-					seenQualities[qualityLabel] = true
+		// Ranked best first, so formats sharing a label with a better one,
+		// e.g. the same resolution in another codec, are dropped
+		for _, format := range d.qualityManager.RankFormats(qualityFormats(info.Formats), quality.Spec{Prefer: quality.Best}) {
+			if format.Height > 0 {
+				label := d.qualityManager.FormatQualityLabel(format.Width, format.Height)
+				spec := quality.Spec{Prefer: quality.Best, MaxHeight: quality.ShortSide(format.Width, format.Height)}
+
+				if !seenQualities[label] {
+					options = append(options, qualityOption(format, spec, label, info.Duration))
+					seenQualities[label] = true
 				}
 			}
 		}
+		qualities = append(qualities, d.qualityManager.SortQualitiesByResolution(options)...)
 
 		// If no video formats found, try to add based on format IDs
 		if len(seenQualities) == 0 {
-			fmt.Printf("DEBUG: No video formats with height found, checking format IDs\n")
+			d.logger.WithField("url", url).Debug("No video formats with height found, listing format IDs")
 			for _, format := range info.Formats {
-				fmt.Printf("DEBUG: Format ID: %s, Height: %d, VCodec: %s, ACodec: %s\n",
-					format.FormatID, format.Height, format.VCodec, format.ACodec)
-
 				// Add formats with specific IDs as quality options
-				if format.FormatID != "" {
+				spec := quality.Spec{Prefer: quality.Best, FormatID: format.FormatID}
+				if format.FormatID != "" && spec.Matches(format.qualityFormat()) {
 					label := format.FormatID
 					if format.Height > 0 {
 						label = fmt.Sprintf("%s (%dp)", format.FormatID, format.Height)
 					}
-					qualities = append(qualities, models.QualityOption{
-						Quality: format.FormatID,
-						Label:   label,
-					})
+					qualities = append(qualities, qualityOption(format.qualityFormat(), spec, label, info.Duration))
 				}
 			}
 		}
 	} else {
-		fmt.Printf("DEBUG: No formats found in yt-dlp output\n")
+		d.logger.WithField("url", url).Debug("No formats found in yt-dlp output")
 	}
 
 	// Add worst quality option
@@ -379,5 +442,5 @@ func (d *UniversalDownloader) GetAvailableQualities(url string) (*models.Qualiti
 	return &models.QualitiesResponse{
 		Platform:           platform,
 		AvailableQualities: qualities,
-	}, nil
+	}
 }
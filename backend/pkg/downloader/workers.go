@@ -0,0 +1,507 @@
+package downloader
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"vidtogallery/pkg/config"
+)
+
+// workerScript is the Python side of the worker protocol
+//
+//go:embed ytdlp_worker.py
+var workerScript []byte
+
+// errWorkersUnavailable means the worker pool cannot take the job and the
+// caller should run the yt-dlp binary instead
+var errWorkersUnavailable = errors.New("yt-dlp workers unavailable")
+
+const (
+	// workerStartTimeout bounds interpreter startup and the yt-dlp import
+	workerStartTimeout = 30 * time.Second
+	// workerPingTimeout bounds a health check of an idle worker
+	workerPingTimeout = 5 * time.Second
+	// maxStartFailures is how many workers may fail to start in a row before
+	// the pool is considered unhealthy until the next health check
+	maxStartFailures = 3
+)
+
+// workerPool keeps long-lived Python processes with yt-dlp imported, so
+// extractions skip the interpreter and extractor startup. Workers are
+// started on demand, recycled after a number of jobs and health checked
+// while idle. When workers cannot be started, jobs fail with
+// errWorkersUnavailable and are run through the yt-dlp binary instead.
+type workerPool struct {
+	python     string
+	script     string
+	size       int
+	maxJobs    int
+	interval   time.Duration
+	minVersion string
+	logger     *logrus.Logger
+
+	idle chan *ytdlpWorker
+	// freed wakes up callers waiting for a worker when a slot frees up
+	freed chan struct{}
+	stop  chan struct{}
+
+	mu       sync.Mutex
+	live     int
+	failures int
+	closed   bool
+	// modulePath is put on PYTHONPATH so workers import yt-dlp from a
+	// swapped-in release binary, which is a Python zip app
+	modulePath string
+	// generation changes with modulePath, retiring workers started before
+	generation    int
+	latestVersion string
+}
+
+// ytdlpWorker is one Python process of the pool
+type ytdlpWorker struct {
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	stdout     *bufio.Reader
+	exited     chan struct{}
+	version    string
+	generation int
+	jobs       int
+	nextID     int
+}
+
+// workerRequest and workerResponse are the JSON lines of the protocol
+// described in ytdlp_worker.py
+type workerRequest struct {
+	ID   int      `json:"id"`
+	Args []string `json:"args,omitempty"`
+	Ping bool     `json:"ping,omitempty"`
+}
+
+type workerResponse struct {
+	ID      int             `json:"id"`
+	Ready   bool            `json:"ready,omitempty"`
+	OK      bool            `json:"ok"`
+	Version string          `json:"version,omitempty"`
+	Log     string          `json:"log,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Info    json.RawMessage `json:"info,omitempty"`
+}
+
+// newWorkerPool writes the worker script to a temporary file and returns a
+// pool that starts its workers on demand, or nil when workers are disabled
+func newWorkerPool(cfg *config.Config, logger *logrus.Logger) *workerPool {
+	if cfg.Workers.Size <= 0 {
+		return nil
+	}
+
+	script, err := os.CreateTemp("", "vidtogallery-ytdlp-worker-*.py")
+	if err == nil {
+		_, err = script.Write(workerScript)
+		if closeErr := script.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		logger.WithError(err).Warn("Failed to write yt-dlp worker script, running yt-dlp per extraction")
+		return nil
+	}
+
+	p := &workerPool{
+		python:     cfg.Workers.Python,
+		script:     script.Name(),
+		size:       cfg.Workers.Size,
+		maxJobs:    cfg.Workers.MaxJobs,
+		interval:   cfg.Workers.HealthInterval,
+		minVersion: cfg.YtDlp.MinVersion,
+		logger:     logger,
+		idle:       make(chan *ytdlpWorker, cfg.Workers.Size),
+		freed:      make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+	}
+	if p.interval > 0 {
+		go p.healthLoop()
+	}
+	return p
+}
+
+// run extracts with yt-dlp args on a worker and returns the info JSON.
+// Jobs fail with errWorkersUnavailable when the pool is unhealthy or the
+// worker died, as opposed to yt-dlp itself reporting an error.
+func (p *workerPool) run(ctx context.Context, args []string) ([]byte, error) {
+	if p == nil {
+		return nil, errWorkersUnavailable
+	}
+
+	w, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := w.call(ctx, workerRequest{Args: args})
+	if err != nil {
+		p.discard(w)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		p.logger.WithError(err).Warn("yt-dlp worker failed, falling back to yt-dlp binary")
+		return nil, fmt.Errorf("%w: %v", errWorkersUnavailable, err)
+	}
+
+	w.jobs++
+	p.release(w)
+
+	if !resp.OK {
+		return nil, fmt.Errorf("yt-dlp failed: %s", resp.Error)
+	}
+	return resp.Info, nil
+}
+
+// acquire returns an idle worker, starting one while the pool is below its size
+func (p *workerPool) acquire(ctx context.Context) (*ytdlpWorker, error) {
+	for {
+		select {
+		case w := <-p.idle:
+			return w, nil
+		default:
+		}
+
+		p.mu.Lock()
+		switch {
+		case p.closed, p.failures >= maxStartFailures:
+			p.mu.Unlock()
+			return nil, errWorkersUnavailable
+		case p.live < p.size:
+			p.live++
+			p.mu.Unlock()
+			return p.start()
+		}
+		p.mu.Unlock()
+
+		select {
+		case w := <-p.idle:
+			return w, nil
+		case <-p.freed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// start launches a worker for a slot already counted in live
+func (p *workerPool) start() (*ytdlpWorker, error) {
+	p.mu.Lock()
+	modulePath, generation := p.modulePath, p.generation
+	p.mu.Unlock()
+
+	w, err := startWorker(p.python, p.script, modulePath)
+	if err == nil && p.minVersion != "" && compareVersions(w.version, p.minVersion) < 0 {
+		w.close()
+		err = fmt.Errorf("yt-dlp module %s is older than the minimum version %s", w.version, p.minVersion)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.live--
+		p.failures++
+		if p.failures == maxStartFailures {
+			p.logger.WithError(err).Warn("yt-dlp workers failed to start, running yt-dlp per extraction until the next health check")
+		}
+		return nil, fmt.Errorf("%w: %v", errWorkersUnavailable, err)
+	}
+	p.failures = 0
+	p.latestVersion = w.version
+	w.generation = generation
+	p.logger.WithField("version", w.version).Debug("Started yt-dlp worker")
+	return w, nil
+}
+
+// release returns w to the idle workers, or recycles it after maxJobs jobs
+// or when the yt-dlp module changed since it started
+func (p *workerPool) release(w *ytdlpWorker) {
+	p.mu.Lock()
+	retired := p.closed || w.generation != p.generation
+	p.mu.Unlock()
+
+	if retired || (p.maxJobs > 0 && w.jobs >= p.maxJobs) {
+		go p.discard(w)
+		return
+	}
+	p.idle <- w
+}
+
+// discard stops w and frees its slot
+func (p *workerPool) discard(w *ytdlpWorker) {
+	w.close()
+
+	p.mu.Lock()
+	p.live--
+	p.mu.Unlock()
+
+	select {
+	case p.freed <- struct{}{}:
+	default:
+	}
+}
+
+// healthLoop pings idle workers and, after workers failed to start, tries
+// starting one again so the pool recovers once the cause is fixed
+func (p *workerPool) healthLoop() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			return
+		}
+
+		p.checkIdle()
+
+		p.mu.Lock()
+		retry := p.failures >= maxStartFailures && p.live < p.size
+		if retry {
+			p.failures = maxStartFailures - 1
+			p.live++
+		}
+		p.mu.Unlock()
+
+		if retry {
+			if w, err := p.start(); err == nil {
+				p.logger.Info("yt-dlp workers recovered")
+				p.release(w)
+			}
+		}
+	}
+}
+
+// checkIdle pings every worker that is idle right now
+func (p *workerPool) checkIdle() {
+	var checked []*ytdlpWorker
+	defer func() {
+		for _, w := range checked {
+			p.release(w)
+		}
+	}()
+
+	for {
+		var w *ytdlpWorker
+		select {
+		case w = <-p.idle:
+		default:
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), workerPingTimeout)
+		_, err := w.call(ctx, workerRequest{Ping: true})
+		cancel()
+		if err != nil {
+			p.logger.WithError(err).Warn("yt-dlp worker failed health check")
+			p.discard(w)
+			continue
+		}
+		checked = append(checked, w)
+	}
+}
+
+// reload makes workers import yt-dlp from the binary, which takes effect
+// as idle workers are replaced and busy ones finish their job
+func (p *workerPool) reload(binary ytdlpBinary) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.modulePath = binary.path
+	p.generation++
+	// Give workers another chance, the new module may fix what failed
+	if p.failures >= maxStartFailures {
+		p.failures = maxStartFailures - 1
+	}
+	p.mu.Unlock()
+
+	for {
+		select {
+		case w := <-p.idle:
+			go p.discard(w)
+		default:
+			return
+		}
+	}
+}
+
+// version returns the yt-dlp version the last started worker imported
+func (p *workerPool) version() string {
+	if p == nil {
+		return ""
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.latestVersion
+}
+
+// Close stops the idle workers. Busy workers stop when their job finishes.
+func (p *workerPool) Close() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	close(p.stop)
+	for {
+		select {
+		case w := <-p.idle:
+			p.discard(w)
+		default:
+			os.Remove(p.script)
+			return
+		}
+	}
+}
+
+// startWorker launches the worker script and waits for its ready line.
+// modulePath, when set, is searched for yt-dlp before the installed module.
+func startWorker(python, script, modulePath string) (*ytdlpWorker, error) {
+	cmd := exec.Command(python, "-u", script)
+	if modulePath != "" {
+		pythonPath := modulePath
+		if existing := os.Getenv("PYTHONPATH"); existing != "" {
+			pythonPath += string(os.PathListSeparator) + existing
+		}
+		cmd.Env = append(os.Environ(), "PYTHONPATH="+pythonPath)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	w := &ytdlpWorker{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+		exited: make(chan struct{}),
+	}
+	go func() {
+		cmd.Wait()
+		close(w.exited)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), workerStartTimeout)
+	defer cancel()
+
+	resp, err := w.read(ctx, func(resp workerResponse) bool { return resp.Ready })
+	if err != nil {
+		w.close()
+		return nil, fmt.Errorf("worker did not start: %w", err)
+	}
+	w.version = resp.Version
+	return w, nil
+}
+
+// call sends req and waits for its response, reporting log lines as
+// progress. The worker is killed when ctx ends, since yt-dlp cannot be
+// interrupted in the middle of an extraction.
+func (w *ytdlpWorker) call(ctx context.Context, req workerRequest) (workerResponse, error) {
+	w.nextID++
+	req.ID = w.nextID
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return workerResponse{}, err
+	}
+	if _, err := w.stdin.Write(append(data, '\n')); err != nil {
+		return workerResponse{}, err
+	}
+
+	return w.read(ctx, func(resp workerResponse) bool {
+		if resp.ID != req.ID {
+			return false
+		}
+		if resp.Log != "" {
+			if progress, ok := parseYtDlpLine(resp.Log); ok {
+				reportProgress(ctx, progress)
+			}
+			return false
+		}
+		return true
+	})
+}
+
+// read returns the first response line accepted by done
+func (w *ytdlpWorker) read(ctx context.Context, done func(workerResponse) bool) (workerResponse, error) {
+	type result struct {
+		resp workerResponse
+		err  error
+	}
+	results := make(chan result, 1)
+
+	go func() {
+		for {
+			line, err := w.stdout.ReadBytes('\n')
+			if err != nil {
+				results <- result{err: fmt.Errorf("worker exited: %w", err)}
+				return
+			}
+
+			var resp workerResponse
+			if err := json.Unmarshal(line, &resp); err != nil {
+				results <- result{err: fmt.Errorf("invalid worker response: %w", err)}
+				return
+			}
+			if done(resp) {
+				results <- result{resp: resp}
+				return
+			}
+		}
+	}()
+
+	select {
+	case r := <-results:
+		return r.resp, r.err
+	case <-ctx.Done():
+		// Unblock the reader before returning so it never reads a later job's lines
+		w.cmd.Process.Kill()
+		<-results
+		return workerResponse{}, ctx.Err()
+	}
+}
+
+// close asks the worker to exit by closing its stdin and kills it if it
+// does not within killDelay
+func (w *ytdlpWorker) close() {
+	w.stdin.Close()
+
+	select {
+	case <-w.exited:
+	case <-time.After(killDelay):
+		w.cmd.Process.Kill()
+		<-w.exited
+	}
+}
@@ -0,0 +1,73 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"vidtogallery/internal/models"
+)
+
+// stderrLimit caps how much yt-dlp stderr is kept for error messages
+const stderrLimit = 16 * 1024
+
+// killDelay is how long yt-dlp may take to exit after SIGTERM before it is killed
+const killDelay = 5 * time.Second
+
+// "[instagram] DAbc123: Downloading JSON metadata"
+var ytdlpStatusPattern = regexp.MustCompile(`^\[([^\]]+)\]\s*(.*)$`)
+
+// runYtDlp runs yt-dlp and returns its stdout, which --dump-json keeps to
+// the JSON info alone. The start of stderr is kept for the error message.
+func runYtDlp(ctx context.Context, path string, args []string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, path, args...)
+	// Let yt-dlp stop its own ffmpeg children before falling back to SIGKILL
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = killDelay
+
+	var stdout bytes.Buffer
+	stderr := &limitedBuffer{limit: stderrLimit}
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return nil, fmt.Errorf("yt-dlp failed: %w, stderr: %s", err, stderr.String())
+		}
+		return nil, fmt.Errorf("yt-dlp failed: %w", err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it and drops the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// parseYtDlpLine turns a yt-dlp status message forwarded by a worker into
+// an extracting progress update. Without --download, extraction is the only
+// stage yt-dlp goes through.
+func parseYtDlpLine(line string) (models.Progress, bool) {
+	line = strings.TrimSpace(line)
+	match := ytdlpStatusPattern.FindStringSubmatch(line)
+	if match == nil || match[1] == "debug" {
+		return models.Progress{}, false
+	}
+	return models.Progress{Stage: models.StageExtracting, Message: line}, true
+}
@@ -0,0 +1,86 @@
+"""Long-lived yt-dlp worker speaking JSON lines over stdin and stdout.
+
+Importing yt-dlp and its extractors takes seconds on small machines, so the
+Go server keeps a few of these processes around instead of running the
+yt-dlp binary for every extraction.
+
+Requests, one per line:
+    {"id": 1, "args": ["--no-playlist", "https://..."]}   extract, like --dump-json
+    {"id": 2, "ping": true}                               health check
+
+Every request gets exactly one response line with its id and "ok", which
+may be preceded by {"id": 1, "log": "..."} lines with yt-dlp status
+messages. The first line after startup is {"ready": true, "version": "..."}.
+"""
+
+import json
+import os
+import sys
+
+# yt-dlp and some extractors print to stdout, which belongs to the protocol,
+# so anything else written there goes to stderr instead
+protocol = os.fdopen(os.dup(sys.stdout.fileno()), "w", encoding="utf-8")
+os.dup2(sys.stderr.fileno(), sys.stdout.fileno())
+
+import yt_dlp  # noqa: E402
+from yt_dlp.version import __version__ as version  # noqa: E402
+
+
+def send(message):
+    protocol.write(json.dumps(message) + "\n")
+    protocol.flush()
+
+
+class Logger:
+    """Forwards yt-dlp messages as log lines of one request"""
+
+    def __init__(self, request_id):
+        self.request_id = request_id
+
+    def debug(self, message):
+        send({"id": self.request_id, "log": message})
+
+    info = debug
+    warning = debug
+    error = debug
+
+
+def extract(request_id, args):
+    parsed = yt_dlp.parse_options(args)
+    if len(parsed.urls) != 1:
+        raise ValueError("expected exactly one URL, got %d" % len(parsed.urls))
+
+    options = dict(parsed.ydl_opts)
+    options["logger"] = Logger(request_id)
+    with yt_dlp.YoutubeDL(options) as ydl:
+        info = ydl.extract_info(parsed.urls[0], download=False)
+        return ydl.sanitize_info(info)
+
+
+def main():
+    send({"ready": True, "version": version})
+
+    for line in sys.stdin:
+        line = line.strip()
+        if not line:
+            continue
+
+        request = json.loads(line)
+        request_id = request.get("id")
+        if request.get("ping"):
+            send({"id": request_id, "ok": True, "version": version})
+            continue
+
+        try:
+            info = extract(request_id, request.get("args") or [])
+        except SystemExit as e:
+            # Option parsing errors exit instead of raising
+            send({"id": request_id, "ok": False, "error": "invalid arguments (exit %s)" % e.code})
+        except Exception as e:
+            send({"id": request_id, "ok": False, "error": str(e)})
+        else:
+            send({"id": request_id, "ok": True, "info": info})
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,226 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Event names sent to subscribers
+const (
+	EventProgress = "progress"
+	EventDone     = "done"
+	EventError    = "error"
+)
+
+// bufferSize is how many recent events each stream keeps for replay
+const bufferSize = 256
+
+// Event is one server-sent event. IDs increase by one within a stream so a
+// reconnecting client can resume after the last ID it saw.
+type Event struct {
+	ID   int64
+	Name string
+	Data []byte
+}
+
+// Broker fans out progress events per job or download to any number of
+// subscribers and keeps the latest events of each stream for reconnects
+type Broker struct {
+	retention time.Duration
+
+	mu      sync.Mutex
+	streams map[string]*stream
+}
+
+type stream struct {
+	events      []Event
+	lastID      int64
+	closed      bool
+	notify      chan struct{}
+	subscribers int
+	updatedAt   time.Time
+}
+
+// Subscription reads the events of one stream in order
+type Subscription struct {
+	broker *Broker
+	stream *stream
+	lastID int64
+}
+
+// NewBroker creates a broker that keeps finished or idle streams for retention
+func NewBroker(retention time.Duration) *Broker {
+	return &Broker{
+		retention: retention,
+		streams:   make(map[string]*stream),
+	}
+}
+
+// JobKey is the stream key of a job
+func JobKey(id string) string {
+	return "job:" + id
+}
+
+// DownloadKey is the stream key of a proxy download
+func DownloadKey(progressID string) string {
+	return "download:" + progressID
+}
+
+// Publish appends an event with JSON encoded data to the stream for key.
+// Events published after the stream was closed are dropped.
+func (b *Broker) Publish(key string, name string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := b.stream(key)
+	if s.closed {
+		return nil
+	}
+
+	s.lastID++
+	s.events = append(s.events, Event{ID: s.lastID, Name: name, Data: payload})
+	if len(s.events) > bufferSize {
+		s.events = s.events[len(s.events)-bufferSize:]
+	}
+	s.updatedAt = time.Now()
+	s.broadcast()
+	return nil
+}
+
+// Close ends the stream for key once subscribers have read its events. The
+// stream stays available for replay until the retention period passes.
+func (b *Broker) Close(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	s := b.stream(key)
+	s.closed = true
+	s.updatedAt = now
+	s.broadcast()
+
+	b.prune(now)
+}
+
+// CloseAll ends every stream so that open subscriptions finish, e.g. when
+// the server shuts down
+func (b *Broker) CloseAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	for _, s := range b.streams {
+		if !s.closed {
+			s.closed = true
+			s.updatedAt = now
+			s.broadcast()
+		}
+	}
+}
+
+// Exists reports whether the broker has a stream for key
+func (b *Broker) Exists(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, ok := b.streams[key]
+	return ok
+}
+
+// Subscribe returns a subscription to key that starts after lastEventID.
+// Subscribing before anything is published waits for the first event.
+func (b *Broker) Subscribe(key string, lastEventID int64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.prune(time.Now())
+	s := b.stream(key)
+	s.subscribers++
+
+	// IDs from an earlier stream with the same key, e.g. before a restart
+	if lastEventID > s.lastID {
+		lastEventID = 0
+	}
+
+	return &Subscription{broker: b, stream: s, lastID: lastEventID}
+}
+
+// Next returns the events published since the previous call. It blocks until
+// there is at least one, the stream is closed, or ctx ends. done reports that
+// the stream is closed and every event has been returned.
+func (sub *Subscription) Next(ctx context.Context) (events []Event, done bool) {
+	for {
+		sub.broker.mu.Lock()
+		s := sub.stream
+		for _, event := range s.events {
+			if event.ID > sub.lastID {
+				events = append(events, event)
+			}
+		}
+		if len(events) > 0 {
+			sub.lastID = events[len(events)-1].ID
+		}
+		closed := s.closed
+		notify := s.notify
+		sub.broker.mu.Unlock()
+
+		if len(events) > 0 || closed {
+			return events, closed
+		}
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// Close releases the subscription
+func (sub *Subscription) Close() {
+	sub.broker.mu.Lock()
+	sub.stream.subscribers--
+	sub.stream.updatedAt = time.Now()
+	sub.broker.mu.Unlock()
+}
+
+// stream returns the stream for key, creating it if needed.
+// The caller must hold b.mu.
+func (b *Broker) stream(key string) *stream {
+	s, ok := b.streams[key]
+	if !ok {
+		s = &stream{
+			notify:    make(chan struct{}),
+			updatedAt: time.Now(),
+		}
+		b.streams[key] = s
+	}
+	return s
+}
+
+// prune forgets streams nobody is reading that were closed, or that never
+// received an event, longer than the retention period ago. Streams that are
+// still being published to are kept so event IDs never restart mid-stream.
+// The caller must hold b.mu.
+func (b *Broker) prune(now time.Time) {
+	for key, s := range b.streams {
+		idle := s.closed || s.lastID == 0
+		if idle && s.subscribers == 0 && now.Sub(s.updatedAt) > b.retention {
+			delete(b.streams, key)
+		}
+	}
+}
+
+// broadcast wakes every subscriber waiting for new events.
+// The caller must hold b.mu.
+func (s *stream) broadcast() {
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
@@ -0,0 +1,462 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"vidtogallery/internal/models"
+	"vidtogallery/pkg/cache"
+	"vidtogallery/pkg/config"
+	"vidtogallery/pkg/downloader"
+	"vidtogallery/pkg/events"
+	"vidtogallery/pkg/webhook"
+)
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrJobFinished    = errors.New("job has already finished")
+	ErrInvalidJobType = errors.New("invalid job type")
+	ErrShuttingDown   = errors.New("job manager is shutting down")
+)
+
+// persistTimeout bounds each write of job state to Redis
+const persistTimeout = 5 * time.Second
+
+// requeueGrace is how long interrupted jobs may take to persist themselves
+// as queued after Shutdown cancels them
+const requeueGrace = 5 * time.Second
+
+// Manager runs extraction and download jobs in the background and persists
+// their state in Redis so clients can poll for results after reconnecting.
+// Progress is also published to the event broker for live streaming.
+type Manager struct {
+	downloaderService *downloader.Service
+	cacheService      *cache.Service
+	events            *events.Broker
+	notifier          *webhook.Notifier
+	logger            *logrus.Logger
+	ttl               time.Duration
+
+	mu      sync.Mutex
+	jobs    map[string]*entry
+	closing bool
+	running sync.WaitGroup
+}
+
+// entry is a job owned by this process
+type entry struct {
+	job    models.Job
+	cancel context.CancelFunc
+	// requeue is set when shutdown interrupts the job, so it is persisted
+	// as queued for the next instance instead of failing
+	requeue bool
+
+	// version counts updates of job. Snapshots are persisted outside m.mu,
+	// so persistMu orders the writes and ones older than persisted are
+	// skipped instead of overwriting newer state.
+	version   uint64
+	persistMu sync.Mutex
+	persisted uint64
+}
+
+func NewManager(downloaderService *downloader.Service, cacheService *cache.Service, broker *events.Broker, notifier *webhook.Notifier, cfg *config.Config, logger *logrus.Logger) *Manager {
+	return &Manager{
+		downloaderService: downloaderService,
+		cacheService:      cacheService,
+		events:            broker,
+		notifier:          notifier,
+		logger:            logger,
+		ttl:               cfg.Jobs.TTL,
+		jobs:              make(map[string]*entry),
+	}
+}
+
+// Submit creates a job for req and starts it in the background. A callback
+// URL that the webhook notifier rejects fails the submission.
+func (m *Manager) Submit(req models.JobRequest) (*models.Job, error) {
+	switch req.Type {
+	case "":
+		req.Type = models.JobTypeExtract
+	case models.JobTypeExtract, models.JobTypeDownload:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidJobType, req.Type)
+	}
+
+	spec, err := m.downloaderService.ResolveQuality(req.URL, req.Quality)
+	if err != nil {
+		return nil, err
+	}
+	req.Quality = spec.String()
+
+	if req.CallbackURL != "" {
+		if err := m.notifier.ValidateURL(req.CallbackURL); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	job := models.Job{
+		ID:            uuid.NewString(),
+		Type:          req.Type,
+		State:         models.JobStateQueued,
+		URL:           req.URL,
+		Quality:       req.Quality,
+		QualityReason: req.QualityReason,
+		CallbackURL:   req.CallbackURL,
+		Progress:      models.Progress{Stage: models.StageQueued},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	ctx, err := m.register(job)
+	if err != nil {
+		return nil, err
+	}
+	m.persist(&job)
+	m.publish(job.ID, events.EventProgress, job.Progress)
+
+	go m.run(ctx, job.ID)
+
+	return &job, nil
+}
+
+// Resume restarts jobs that a previous instance persisted as queued. Each
+// job is claimed atomically, so instances sharing Redis never run the same
+// job twice.
+func (m *Manager) Resume(ctx context.Context) error {
+	ids, err := m.cacheService.QueuedJobs(ctx)
+	if err != nil {
+		if errors.Is(err, cache.ErrCacheDisabled) {
+			return nil
+		}
+		return err
+	}
+
+	resumed := 0
+	for _, id := range ids {
+		claimed, err := m.cacheService.ClaimQueuedJob(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		job, err := m.cacheService.GetJob(ctx, id)
+		if err != nil {
+			if errors.Is(err, cache.ErrCacheNotFound) {
+				continue
+			}
+			return err
+		}
+		if job.State != models.JobStateQueued {
+			continue
+		}
+
+		runCtx, err := m.register(*job)
+		if err != nil {
+			return err
+		}
+		go m.run(runCtx, job.ID)
+		resumed++
+	}
+
+	if resumed > 0 {
+		m.logger.WithField("jobs", resumed).Info("Resumed queued jobs")
+	}
+	return nil
+}
+
+// register records job as owned by this process and returns the context to
+// run it with. The caller must start m.run for it.
+func (m *Manager) register(job models.Job) (context.Context, error) {
+	m.mu.Lock()
+	if m.closing {
+		m.mu.Unlock()
+		return nil, ErrShuttingDown
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.prune(time.Now())
+	m.jobs[job.ID] = &entry{job: job, cancel: cancel}
+	m.running.Add(1)
+	m.mu.Unlock()
+
+	return ctx, nil
+}
+
+// Shutdown stops accepting jobs and waits for running ones to finish. When
+// ctx ends first, the remaining jobs are cancelled and persisted as queued
+// so that Resume picks them up on the next start, and ctx.Err() is returned.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.closing = true
+	m.mu.Unlock()
+
+	idle := make(chan struct{})
+	go func() {
+		m.running.Wait()
+		close(idle)
+	}()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+	}
+
+	m.mu.Lock()
+	interrupted := 0
+	for _, e := range m.jobs {
+		if !e.job.Finished() {
+			e.requeue = true
+			e.cancel()
+			interrupted++
+		}
+	}
+	m.mu.Unlock()
+
+	m.logger.WithField("jobs", interrupted).Warn("Shutdown deadline reached, requeueing running jobs")
+
+	select {
+	case <-idle:
+	case <-time.After(requeueGrace):
+		m.logger.Warn("Interrupted jobs did not exit after cancellation")
+	}
+	return ctx.Err()
+}
+
+// Get returns the current state of a job
+func (m *Manager) Get(ctx context.Context, id string) (*models.Job, error) {
+	m.mu.Lock()
+	if e, ok := m.jobs[id]; ok {
+		job := e.job
+		m.mu.Unlock()
+		return &job, nil
+	}
+	m.mu.Unlock()
+
+	job, err := m.cacheService.GetJob(ctx, id)
+	if err != nil {
+		if errors.Is(err, cache.ErrCacheNotFound) || errors.Is(err, cache.ErrCacheDisabled) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// Cancel stops a queued or running job
+func (m *Manager) Cancel(ctx context.Context, id string) (*models.Job, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	m.mu.Unlock()
+
+	if ok {
+		// Check the state in the same update that cancels, so a job
+		// finishing concurrently is not overwritten as cancelled
+		job, err := m.update(id, func(job *models.Job) error {
+			if job.Finished() {
+				return ErrJobFinished
+			}
+			job.State = models.JobStateCancelled
+			job.Error = context.Canceled.Error()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		e.cancel()
+		return job, nil
+	}
+
+	// Not owned by this process, e.g. left behind by a previous instance
+	job, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Finished() {
+		return nil, ErrJobFinished
+	}
+
+	job.State = models.JobStateCancelled
+	job.Error = context.Canceled.Error()
+	job.UpdatedAt = time.Now()
+	m.persist(job)
+	return job, nil
+}
+
+func (m *Manager) run(ctx context.Context, id string) {
+	defer m.running.Done()
+
+	m.mu.Lock()
+	job := m.jobs[id].job
+	m.mu.Unlock()
+
+	ctx = downloader.WithProgress(ctx, func(progress models.Progress) {
+		updated, err := m.update(id, func(job *models.Job) error {
+			if job.Finished() {
+				return ErrJobFinished
+			}
+			if job.State == models.JobStateQueued {
+				job.State = models.JobStateRunning
+			}
+			job.Progress = progress
+			return nil
+		})
+		if err == nil {
+			m.publish(id, events.EventProgress, updated.Progress)
+		}
+	})
+
+	var video *models.VideoResponse
+	err := m.whenQueued(ctx, func() (err error) {
+		video, err = m.downloaderService.ProcessURLWithQuality(ctx, job.URL, job.Quality)
+		return err
+	})
+
+	var file *models.VideoFile
+	if err == nil && job.Type == models.JobTypeDownload {
+		err = m.whenQueued(ctx, func() (err error) {
+			file, err = m.downloaderService.DownloadFile(ctx, &models.ProxyDownloadRequest{
+				VideoURL:  video.VideoURL,
+				SourceURL: job.URL,
+				Quality:   job.Quality,
+			})
+			return err
+		})
+	}
+
+	if m.requeued(id) {
+		m.update(id, func(job *models.Job) error {
+			job.State = models.JobStateQueued
+			job.Progress = models.Progress{Stage: models.StageQueued}
+			job.Error = ""
+			return nil
+		})
+		m.logger.WithField("job_id", id).Info("Job interrupted by shutdown, requeued")
+		return
+	}
+
+	finished, _ := m.update(id, func(job *models.Job) error {
+		if job.State == models.JobStateCancelled {
+			return nil
+		}
+		job.Progress.Stage = models.StageDone
+		if err != nil {
+			job.State = models.JobStateFailed
+			job.Error = err.Error()
+			return nil
+		}
+		job.State = models.JobStateSucceeded
+		job.Result = video
+		if job.QualityReason != "" {
+			job.Result = downloader.WithQualityReason(video, job.QualityReason)
+		}
+		job.File = file
+		return nil
+	})
+	m.publish(id, events.EventDone, finished)
+	m.events.Close(events.JobKey(id))
+
+	if finished.CallbackURL != "" {
+		m.notifier.Deliver(finished.CallbackURL, models.WebhookPayload{
+			Event: models.WebhookEventJobFinished,
+			JobID: finished.ID,
+			State: finished.State,
+			Video: finished.Result,
+			File:  finished.File,
+			Error: finished.Error,
+		})
+	}
+
+	if err != nil && !errors.Is(err, context.Canceled) {
+		m.logger.WithError(err).WithFields(logrus.Fields{
+			"job_id": id,
+			"url":    job.URL,
+		}).Error("Job failed")
+	}
+}
+
+// whenQueued runs fn, trying again after the service's retry delay for as
+// long as the worker queue is full. A job the API has accepted waits for a
+// worker instead of failing like a synchronous request would.
+func (m *Manager) whenQueued(ctx context.Context, fn func() error) error {
+	for {
+		err := fn()
+		if !errors.Is(err, downloader.ErrQueueFull) {
+			return err
+		}
+
+		select {
+		case <-time.After(m.downloaderService.RetryAfter()):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// requeued reports whether shutdown interrupted the job
+func (m *Manager) requeued(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.jobs[id].requeue
+}
+
+// update applies fn to an owned job, persists it and returns a copy. When
+// fn returns an error the job is left unchanged and the error is returned.
+func (m *Manager) update(id string, fn func(job *models.Job) error) (*models.Job, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return nil, ErrJobNotFound
+	}
+	if err := fn(&e.job); err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	e.job.UpdatedAt = time.Now()
+	e.version++
+	job, version := e.job, e.version
+	m.mu.Unlock()
+
+	e.persistMu.Lock()
+	defer e.persistMu.Unlock()
+	if version > e.persisted {
+		m.persist(&job)
+		e.persisted = version
+	}
+	return &job, nil
+}
+
+func (m *Manager) publish(id string, name string, data interface{}) {
+	if err := m.events.Publish(events.JobKey(id), name, data); err != nil {
+		m.logger.WithError(err).WithField("job_id", id).Warn("Failed to publish job event")
+	}
+}
+
+func (m *Manager) persist(job *models.Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+	defer cancel()
+
+	if err := m.cacheService.SetJob(ctx, job); err != nil && !errors.Is(err, cache.ErrCacheDisabled) {
+		m.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to persist job")
+	}
+}
+
+// prune forgets finished jobs older than the job TTL. Without Redis these
+// in-memory records are the only copy, so they are kept until then.
+// The caller must hold m.mu.
+func (m *Manager) prune(now time.Time) {
+	for id, e := range m.jobs {
+		if e.job.Finished() && now.Sub(e.job.UpdatedAt) > m.ttl {
+			delete(m.jobs, id)
+		}
+	}
+}
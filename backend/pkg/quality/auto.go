@@ -0,0 +1,111 @@
+package quality
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Auto is the quality that picks a spec from the requester's client hints
+const Auto = "auto"
+
+// Hints are the client hints and user agent of a request
+type Hints struct {
+	SaveData bool
+	// ECT is the effective connection type: slow-2g, 2g, 3g or 4g
+	ECT string
+	// Downlink is the estimated bandwidth in Mbit/s
+	Downlink float64
+	// ViewportWidth is in CSS pixels, DPR the device pixels per CSS pixel
+	ViewportWidth int
+	DPR           float64
+	UserAgent     string
+	// Platform is the Sec-CH-UA-Platform hint, e.g. "Android" or "iOS"
+	Platform string
+}
+
+// heightLadder are the usual rendition heights viewport caps round up to
+var heightLadder = []int{240, 360, 480, 720, 1080, 1440, 2160}
+
+var (
+	iosPattern     = regexp.MustCompile(`(?i)iPhone|iPad|iPod`)
+	androidPattern = regexp.MustCompile(`(?i)Android`)
+)
+
+// AutoSpec chooses a spec for hints and explains the choice. Save-Data asks
+// for the smallest file up to 480p, slow connections and small screens cap
+// the height, and iOS devices prefer H.264/AAC MP4 since their photo library
+// cannot import VP9 or AV1.
+func (m *Manager) AutoSpec(h Hints) (Spec, string) {
+	spec := Spec{Prefer: Best}
+	var reasons []string
+
+	capHeight := func(height int) {
+		if spec.MaxHeight == 0 || height < spec.MaxHeight {
+			spec.MaxHeight = height
+		}
+	}
+
+	if h.SaveData {
+		spec.Prefer = Worst
+		capHeight(480)
+		reasons = append(reasons, "save-data")
+	}
+
+	switch ect := strings.ToLower(h.ECT); ect {
+	case "slow-2g", "2g":
+		capHeight(360)
+		reasons = append(reasons, "ect="+ect)
+	case "3g":
+		capHeight(480)
+		reasons = append(reasons, "ect="+ect)
+	}
+
+	// Browsers round downlink and cap it at 10 Mbit/s
+	if h.Downlink > 0 && h.Downlink < 10 {
+		switch {
+		case h.Downlink < 1:
+			capHeight(360)
+		case h.Downlink < 2.5:
+			capHeight(480)
+		case h.Downlink < 5:
+			capHeight(720)
+		default:
+			capHeight(1080)
+		}
+		reasons = append(reasons, fmt.Sprintf("downlink=%gMbps", h.Downlink))
+	}
+
+	if h.ViewportWidth > 0 {
+		dpr := h.DPR
+		if dpr <= 0 {
+			dpr = 1
+		}
+		// The viewport width is the short side a portrait phone shows
+		pixels := int(float64(h.ViewportWidth) * dpr)
+		for _, height := range heightLadder {
+			if height >= pixels {
+				capHeight(height)
+				break
+			}
+		}
+		reasons = append(reasons, fmt.Sprintf("viewport=%dpx", pixels))
+	}
+
+	platform := strings.Trim(h.Platform, `"`)
+	switch {
+	case strings.EqualFold(platform, "iOS") || iosPattern.MatchString(h.UserAgent):
+		spec.Codec = "h264"
+		spec.AudioCodec = "aac"
+		spec.Container = "mp4"
+		reasons = append(reasons, "ios")
+	case strings.EqualFold(platform, "Android") || androidPattern.MatchString(h.UserAgent):
+		spec.Container = "mp4"
+		reasons = append(reasons, "android")
+	}
+
+	if len(reasons) == 0 {
+		return spec, "no client hints"
+	}
+	return spec, strings.Join(reasons, ",")
+}
@@ -2,115 +2,184 @@ package quality
 
 import (
 	"fmt"
+	"slices"
 	"sort"
-	"strconv"
-	"strings"
 
 	"vidtogallery/internal/models"
 )
 
 // Manager handles quality selection and processing logic
-type Manager struct{}
+type Manager struct {
+	profiles     map[string]Profile
+	profileOrder []string
+}
 
-// NewManager creates a new quality manager
+// NewManager creates a new quality manager with the default profiles
 func NewManager() *Manager {
-	return &Manager{}
+	m := &Manager{profiles: make(map[string]Profile)}
+	for _, profile := range DefaultProfiles {
+		m.profiles[profile.Name] = profile
+		m.profileOrder = append(m.profileOrder, profile.Name)
+	}
+	return m
 }
 
-// SelectBestQuality returns the highest quality option from a list
-func (m *Manager) SelectBestQuality(qualities []models.QualityOption) *models.QualityOption {
-	if len(qualities) == 0 {
-		return nil
-	}
+// SortQualitiesByResolution sorts qualities from best to worst, ranking them
+// by CompareFormats like the formats they were listed from
+func (m *Manager) SortQualitiesByResolution(qualities []models.QualityOption) []models.QualityOption {
+	sorted := slices.Clone(qualities)
+	spec := Spec{Prefer: Best}
+	slices.SortStableFunc(sorted, func(a, b models.QualityOption) int {
+		return m.CompareFormats(optionFormat(a), optionFormat(b), spec)
+	})
+	return sorted
+}
 
-	bestQuality := qualities[0]
-	bestResolution := m.calculateResolution(bestQuality.Width, bestQuality.Height)
+// optionFormat describes a quality option as the format it was listed from
+func optionFormat(option models.QualityOption) Format {
+	format := Format{
+		URL:      option.VideoURL,
+		Width:    option.Width,
+		Height:   option.Height,
+		FPS:      option.FPS,
+		VCodec:   option.VCodec,
+		ACodec:   option.ACodec,
+		Ext:      option.Container,
+		FileSize: option.FileSize,
+		Bitrate:  option.Bitrate,
+	}
+	if !option.HasAudio {
+		format.ACodec = "none"
+	}
+	if option.HDR {
+		format.DynamicRange = "HDR"
+	}
+	return format
+}
 
-	for _, quality := range qualities[1:] {
-		resolution := m.calculateResolution(quality.Width, quality.Height)
-		if resolution > bestResolution {
-			bestQuality = quality
-			bestResolution = resolution
-		}
+// FormatQualityLabel creates a user-friendly label from dimensions. Labels
+// follow the short side, so a 1080x1920 portrait video is "1080p" like its
+// 1920x1080 landscape counterpart.
+func (m *Manager) FormatQualityLabel(width, height int) string {
+	short := ShortSide(width, height)
+	switch {
+	case short >= 2160:
+		return "4K"
+	case short >= 1440:
+		return "1440p"
+	case short >= 1080:
+		return "1080p"
+	case short >= 720:
+		return "720p"
+	case short >= 480:
+		return "480p"
+	case short >= 360:
+		return "360p"
+	default:
+		return fmt.Sprintf("%dp", short)
 	}
+}
 
-	return &bestQuality
+// codecRank orders video codecs by how widely devices can play them back
+var codecRank = map[string]int{
+	"h264": 4,
+	"h265": 3,
+	"vp9":  2,
+	"av1":  1,
 }
 
-// SelectQualityByPreference selects quality based on user preference
-func (m *Manager) SelectQualityByPreference(qualities []models.QualityOption, preference string) *models.QualityOption {
-	if len(qualities) == 0 {
+// SelectFormat returns the format that best satisfies spec, or nil when no
+// format matches its limits
+func (m *Manager) SelectFormat(formats []Format, spec Spec) *Format {
+	ranked := m.RankFormats(formats, spec)
+	if len(ranked) == 0 {
 		return nil
 	}
+	return &ranked[0]
+}
 
-	// If preference is "best" or empty, return best quality
-	if preference == "best" || preference == "" {
-		return m.SelectBestQuality(qualities)
-	}
-
-	// Try to find exact match
-	for _, quality := range qualities {
-		if quality.Quality == preference {
-			return &quality
+// RankFormats returns the formats matching spec, best pick first
+func (m *Manager) RankFormats(formats []Format, spec Spec) []Format {
+	var ranked []Format
+	for _, format := range formats {
+		if spec.Matches(format) {
+			ranked = append(ranked, format)
 		}
 	}
 
-	// Fallback to best quality
-	return m.SelectBestQuality(qualities)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return m.CompareFormats(ranked[i], ranked[j], spec) < 0
+	})
+	return ranked
 }
 
-// SortQualitiesByResolution sorts qualities from highest to lowest resolution
-func (m *Manager) SortQualitiesByResolution(qualities []models.QualityOption) []models.QualityOption {
-	sorted := make([]models.QualityOption, len(qualities))
-	copy(sorted, qualities)
-
-	sort.Slice(sorted, func(i, j int) bool {
-		resI := m.calculateResolution(sorted[i].Width, sorted[i].Height)
-		resJ := m.calculateResolution(sorted[j].Width, sorted[j].Height)
-		return resI > resJ
-	})
+// CompareFormats returns a negative number when a is a better pick than b
+// for spec, a positive one when b is, and 0 when they rank the same. Formats
+// with the preferred codec and container come first, then by resolution
+// (highest for best, lowest known for worst), formats with audio over silent
+// ones, more compatible codecs, and finally bitrate in the direction of the
+// resolution preference.
+func (m *Manager) CompareFormats(a, b Format, spec Spec) int {
+	if pa, pb := spec.Preferred(a), spec.Preferred(b); pa != pb {
+		return better(pa)
+	}
 
-	return sorted
-}
+	resA := m.calculateResolution(a.Width, a.Height)
+	resB := m.calculateResolution(b.Width, b.Height)
+	if resA == 0 && a.Height > 0 {
+		resA = a.Height
+	}
+	if resB == 0 && b.Height > 0 {
+		resB = b.Height
+	}
+	if resA != resB {
+		switch {
+		case resA == 0:
+			// Unknown resolutions never win over known ones
+			return 1
+		case resB == 0:
+			return -1
+		case spec.Prefer == Worst:
+			return better(resA < resB)
+		default:
+			return better(resA > resB)
+		}
+	}
 
-// ParseDimensions extracts width and height from quality string like "1920x1080"
-func (m *Manager) ParseDimensions(quality string) (width, height int, err error) {
-	parts := strings.Split(quality, "x")
-	if len(parts) != 2 {
-		return 0, 0, fmt.Errorf("invalid quality format: %s", quality)
+	if a.HasAudio() != b.HasAudio() {
+		return better(a.HasAudio())
 	}
 
-	width, err = strconv.Atoi(parts[0])
-	if err != nil {
-		return 0, 0, fmt.Errorf("invalid width in quality: %s", quality)
+	if ca, cb := codecRank[codecName(a.VCodec)], codecRank[codecName(b.VCodec)]; ca != cb {
+		return better(ca > cb)
 	}
 
-	height, err = strconv.Atoi(parts[1])
-	if err != nil {
-		return 0, 0, fmt.Errorf("invalid height in quality: %s", quality)
+	if a.Bitrate != b.Bitrate {
+		if spec.Prefer == Worst {
+			return better(a.Bitrate < b.Bitrate)
+		}
+		return better(a.Bitrate > b.Bitrate)
 	}
+	return 0
+}
 
-	return width, height, nil
+// better turns "a wins" into a comparison result
+func better(aWins bool) int {
+	if aWins {
+		return -1
+	}
+	return 1
 }
 
-// FormatQualityLabel creates a user-friendly label from dimensions
-func (m *Manager) FormatQualityLabel(width, height int) string {
-	switch {
-	case height >= 2160:
-		return "4K"
-	case height >= 1440:
-		return "1440p"
-	case height >= 1080:
-		return "1080p"
-	case height >= 720:
-		return "720p"
-	case height >= 480:
-		return "480p"
-	case height >= 360:
-		return "360p"
-	default:
-		return fmt.Sprintf("%dp", height)
+// codecName maps a yt-dlp codec tag to one of the codec names of the spec
+// grammar, or "" when it is not one of them
+func codecName(tag string) string {
+	for codec := range codecTags {
+		if HasCodec(tag, codec) {
+			return codec
+		}
 	}
+	return ""
 }
 
 // calculateResolution returns total pixel count for comparison
@@ -0,0 +1,184 @@
+package quality
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"vidtogallery/internal/models"
+)
+
+// loadFormats reads a format list as dumped by yt-dlp from testdata
+func loadFormats(t *testing.T, name string) []Format {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var info struct {
+		Formats []struct {
+			FormatID    string  `json:"format_id"`
+			URL         string  `json:"url"`
+			Width       int     `json:"width"`
+			Height      int     `json:"height"`
+			FPS         float64 `json:"fps"`
+			VCodec      string  `json:"vcodec"`
+			ACodec      string  `json:"acodec"`
+			Ext         string  `json:"ext"`
+			FileSize    int64   `json:"filesize"`
+			FileSizeEst int64   `json:"filesize_approx"`
+			TBR         float64 `json:"tbr"`
+		} `json:"formats"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		t.Fatal(err)
+	}
+
+	formats := make([]Format, len(info.Formats))
+	for i, f := range info.Formats {
+		size := f.FileSize
+		if size == 0 {
+			size = f.FileSizeEst
+		}
+		formats[i] = Format{
+			ID:       f.FormatID,
+			URL:      f.URL,
+			Width:    f.Width,
+			Height:   f.Height,
+			FPS:      f.FPS,
+			VCodec:   f.VCodec,
+			ACodec:   f.ACodec,
+			Ext:      f.Ext,
+			FileSize: size,
+			Bitrate:  f.TBR,
+		}
+	}
+	return formats
+}
+
+func formatIDs(formats []Format) []string {
+	ids := make([]string, len(formats))
+	for i, format := range formats {
+		ids[i] = format.ID
+	}
+	return ids
+}
+
+func TestRankFormats(t *testing.T) {
+	tests := []struct {
+		file    string
+		quality string
+		want    []string
+	}{
+		{"instagram.json", "best", []string{
+			"1203004327611419v", "1627415664852398v", "101", "896045922353087v", "102",
+		}},
+		{"instagram.json", "worst", []string{
+			"896045922353087v", "102", "1627415664852398v", "101", "1203004327611419v",
+		}},
+		{"tiktok.json", "best", []string{
+			"bytevc1_1080p_1017498-0", "bytevc1_720p_724398-0", "h264_540p_1184556-0", "download_addr-0", "bytevc1_540p_516434-0",
+		}},
+		{"tiktok.json", "best[vcodec=h265]", []string{
+			"bytevc1_1080p_1017498-0", "bytevc1_720p_724398-0", "bytevc1_540p_516434-0", "h264_540p_1184556-0", "download_addr-0",
+		}},
+		{"x.json", "best", []string{
+			"hls-10368", "http-10368", "hls-2176", "http-2176", "hls-832", "http-832", "hls-256", "http-256",
+		}},
+		{"x.json", "best[height<=480]", []string{
+			"hls-832", "http-832", "hls-256", "http-256",
+		}},
+	}
+
+	m := NewManager()
+	for _, tt := range tests {
+		t.Run(tt.file+"/"+tt.quality, func(t *testing.T) {
+			spec, err := m.Resolve(tt.quality)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := formatIDs(m.RankFormats(loadFormats(t, tt.file), spec))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("RankFormats(%s) = %v, want %v", tt.quality, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectFormat(t *testing.T) {
+	tests := []struct {
+		file    string
+		quality string
+		// want is the selected format ID, "" when no format matches
+		want string
+	}{
+		{"instagram.json", "best", "1203004327611419v"},
+		{"instagram.json", "720p", "1627415664852398v"},
+		{"instagram.json", "worst", "896045922353087v"},
+		{"instagram.json", "best[audio]", ""},
+		{"instagram.json", "datasaver", "896045922353087v"},
+		{"instagram.json", "best[format_id=101]", "101"},
+
+		{"tiktok.json", "best", "bytevc1_1080p_1017498-0"},
+		{"tiktok.json", "720p", "bytevc1_720p_724398-0"},
+		{"tiktok.json", "worst", "download_addr-0"},
+		{"tiktok.json", "best[vcodec=h264]", "h264_540p_1184556-0"},
+		{"tiktok.json", "best[vcodec=av1]", "bytevc1_1080p_1017498-0"},
+		{"tiktok.json", "best[vcodec=av1][strict]", ""},
+		{"tiktok.json", "worst[filesize<=2M]", "bytevc1_540p_516434-0"},
+		{"tiktok.json", "balanced", "h264_540p_1184556-0"},
+		{"tiktok.json", "gallery", "h264_540p_1184556-0"},
+
+		{"x.json", "best", "hls-10368"},
+		{"x.json", "720p", "hls-2176"},
+		{"x.json", "worst", "hls-256"},
+		{"x.json", "best[audio]", ""},
+		{"x.json", "best[ext=webm]", "hls-10368"},
+		{"x.json", "best[ext=webm][strict]", ""},
+		{"x.json", "best[height<=100]", ""},
+	}
+
+	m := NewManager()
+	for _, tt := range tests {
+		t.Run(tt.file+"/"+tt.quality, func(t *testing.T) {
+			spec, err := m.Resolve(tt.quality)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := ""
+			if format := m.SelectFormat(loadFormats(t, tt.file), spec); format != nil {
+				got = format.ID
+			}
+			if got != tt.want {
+				t.Errorf("SelectFormat(%s) = %q, want %q", tt.quality, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortQualitiesByResolution(t *testing.T) {
+	qualities := []models.QualityOption{
+		{Quality: "480p", Width: 480, Height: 852, VCodec: "avc1", VideoURL: "https://cdn/480"},
+		{Quality: "1080p", Width: 1080, Height: 1920, VCodec: "avc1", VideoURL: "https://cdn/1080"},
+		{Quality: "720p-audio", Width: 720, Height: 1280, VCodec: "avc1", ACodec: "mp4a", HasAudio: true, VideoURL: "https://cdn/720a"},
+		{Quality: "720p", Width: 720, Height: 1280, VCodec: "avc1", VideoURL: "https://cdn/720"},
+	}
+
+	got := make([]string, 0, len(qualities))
+	for _, option := range NewManager().SortQualitiesByResolution(qualities) {
+		got = append(got, option.Quality)
+	}
+	want := []string{"1080p", "720p-audio", "720p", "480p"}
+	if !slices.Equal(got, want) {
+		t.Errorf("SortQualitiesByResolution() = %v, want %v", got, want)
+	}
+	if qualities[0].Quality != "480p" {
+		t.Error("SortQualitiesByResolution modified its input")
+	}
+}
@@ -0,0 +1,42 @@
+package quality
+
+import "math"
+
+// Video orientations
+const (
+	Landscape = "landscape"
+	Portrait  = "portrait"
+	Square    = "square"
+)
+
+// ShortSide returns the smaller dimension, which is what "720p" refers to
+// regardless of orientation. With an unknown width it is the height.
+func ShortSide(width, height int) int {
+	if width > 0 && width < height {
+		return width
+	}
+	return height
+}
+
+// Orientation returns landscape, portrait or square, or "" for unknown dimensions
+func Orientation(width, height int) string {
+	switch {
+	case width <= 0 || height <= 0:
+		return ""
+	case width > height:
+		return Landscape
+	case width < height:
+		return Portrait
+	default:
+		return Square
+	}
+}
+
+// AspectRatio returns width divided by height rounded to three decimals,
+// e.g. 0.562 for 9:16, or 0 for unknown dimensions
+func AspectRatio(width, height int) float64 {
+	if width <= 0 || height <= 0 {
+		return 0
+	}
+	return math.Round(float64(width)/float64(height)*1000) / 1000
+}
@@ -0,0 +1,105 @@
+package quality
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Profile is a named quality spec defined by the server, so clients can ask
+// for "datasaver" instead of spelling out a selector
+type Profile struct {
+	Name        string
+	Label       string
+	Description string
+	Spec        Spec
+}
+
+// DefaultProfiles are available unless the configuration overrides them
+var DefaultProfiles = []Profile{
+	{
+		Name:        "datasaver",
+		Label:       "Data saver",
+		Description: "Smallest file up to 480p",
+		Spec:        Spec{Prefer: Worst, MaxHeight: 480},
+	},
+	{
+		Name:        "balanced",
+		Label:       "Balanced",
+		Description: "Up to 1080p, preferring H.264",
+		Spec:        Spec{Prefer: Best, MaxHeight: 1080, Codec: "h264"},
+	},
+	{
+		Name:        "original",
+		Label:       "Original",
+		Description: "Best available quality as uploaded",
+		Spec:        Spec{Prefer: Best},
+	},
+	{
+		Name:        "gallery",
+		Label:       "Gallery safe",
+		Description: "H.264/AAC MP4 with audio that any photo gallery can play",
+		Spec:        Spec{Prefer: Best, Codec: "h264", AudioCodec: "aac", Container: "mp4", RequireAudio: true, Strict: true},
+	},
+}
+
+var profileNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// Profiles returns the configured profiles, defaults first and the others
+// in name order
+func (m *Manager) Profiles() []Profile {
+	profiles := make([]Profile, 0, len(m.profiles))
+	for _, name := range m.profileOrder {
+		profiles = append(profiles, m.profiles[name])
+	}
+	return profiles
+}
+
+// Resolve turns a profile name or a spec string into a Spec
+func (m *Manager) Resolve(s string) (Spec, error) {
+	if profile, ok := m.profiles[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return profile.Spec, nil
+	}
+	return Parse(s)
+}
+
+// ConfigureProfiles adds or replaces profiles from name to spec string
+// pairs. Invalid entries are skipped and reported in the returned error
+// while the valid ones still apply. It is meant to be called once at startup.
+func (m *Manager) ConfigureProfiles(specs map[string]string) error {
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if !profileNamePattern.MatchString(name) {
+			errs = append(errs, fmt.Errorf("profile %q: name must be lowercase letters, digits, - or _", name))
+			continue
+		}
+		if _, err := Parse(name); err == nil || name == Auto {
+			errs = append(errs, fmt.Errorf("profile %q: name is already a quality selector", name))
+			continue
+		}
+
+		spec, err := Parse(specs[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("profile %q: %w", name, err))
+			continue
+		}
+
+		profile, ok := m.profiles[name]
+		if !ok {
+			profile = Profile{Name: name, Label: name}
+			m.profileOrder = append(m.profileOrder, name)
+		}
+		profile.Spec = spec
+		profile.Description = spec.String()
+		m.profiles[name] = profile
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,374 @@
+package quality
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidSpec is returned for quality strings that do not follow the spec grammar
+var ErrInvalidSpec = errors.New("invalid quality")
+
+// Preference picks the highest or lowest quality among matching formats
+type Preference string
+
+const (
+	Best  Preference = "best"
+	Worst Preference = "worst"
+)
+
+// Spec is a parsed quality selector. Zero limits mean "no limit".
+//
+// The grammar is a preference optionally followed by filters in brackets,
+// either one per bracket or comma separated:
+//
+//	best | worst | 720p
+//	best[height<=720]
+//	best[height<=1080][vcodec=h264][ext=mp4]
+//	worst[fps<=30,filesize<=50M,audio]
+//	best[vcodec=h264][acodec=aac][ext=mp4][strict]
+//	best[format_id=hd]
+//
+// "720p" is shorthand for best[height<=720]. Height, fps and filesize are
+// hard limits; codecs and container are preferences that fall back to other
+// formats when none match, unless the spec is strict.
+type Spec struct {
+	Prefer Preference
+	// MaxHeight limits the short side, i.e. the height of landscape and the
+	// width of portrait video, so 720p means the same for both
+	MaxHeight    int
+	MaxFPS       int
+	MaxFileSize  int64
+	Codec        string
+	AudioCodec   string
+	Container    string
+	RequireAudio bool
+	// Strict turns the codec and container preferences into requirements
+	Strict   bool
+	FormatID string
+}
+
+// Video codecs accepted by the vcodec filter, with the codec tag prefixes
+// yt-dlp reports for them
+var codecTags = map[string][]string{
+	"h264": {"avc1", "avc", "h264"},
+	"h265": {"hvc1", "hev1", "hevc", "h265"},
+	"vp9":  {"vp09", "vp9"},
+	"av1":  {"av01", "av1"},
+}
+
+// Audio codecs accepted by the acodec filter
+var audioCodecTags = map[string][]string{
+	"aac":  {"mp4a", "aac"},
+	"opus": {"opus"},
+	"mp3":  {"mp3"},
+}
+
+var containers = map[string]bool{
+	"mp4":  true,
+	"webm": true,
+	"mov":  true,
+}
+
+var (
+	shorthandPattern = regexp.MustCompile(`^(\d+)p$`)
+	filterPattern    = regexp.MustCompile(`^([A-Za-z_]+)\s*(<=|=)?\s*(.*)$`)
+	formatIDPattern  = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	sizePattern      = regexp.MustCompile(`^(\d+)\s*([kmg]i?b?|b)?$`)
+)
+
+// Parse parses a quality string. An empty string selects the best quality.
+func Parse(s string) (Spec, error) {
+	s = strings.TrimSpace(s)
+	spec := Spec{Prefer: Best}
+	if s == "" {
+		return spec, nil
+	}
+
+	if match := shorthandPattern.FindStringSubmatch(strings.ToLower(s)); match != nil {
+		height, err := positive(match[1])
+		if err != nil {
+			return Spec{}, invalid(s, "height must be a positive number")
+		}
+		spec.MaxHeight = height
+		return spec, nil
+	}
+
+	base, rest, _ := strings.Cut(s, "[")
+	switch Preference(strings.ToLower(strings.TrimSpace(base))) {
+	case Best:
+	case Worst:
+		spec.Prefer = Worst
+	default:
+		return Spec{}, invalid(s, "must start with best, worst or a height such as 720p")
+	}
+
+	if rest == "" {
+		if strings.Contains(s, "[") {
+			return Spec{}, invalid(s, "unterminated filter")
+		}
+		return spec, nil
+	}
+
+	// rest is "f1][f2,f3]": every bracket must be closed
+	if !strings.HasSuffix(rest, "]") {
+		return Spec{}, invalid(s, "unterminated filter")
+	}
+	for _, group := range strings.Split(strings.TrimSuffix(rest, "]"), "][") {
+		for _, filter := range strings.Split(group, ",") {
+			if err := spec.apply(strings.TrimSpace(filter)); err != nil {
+				return Spec{}, invalid(s, err.Error())
+			}
+		}
+	}
+
+	return spec, nil
+}
+
+// apply adds a single "key<=value", "key=value" or "key" filter to the spec
+func (s *Spec) apply(filter string) error {
+	match := filterPattern.FindStringSubmatch(filter)
+	if match == nil {
+		return fmt.Errorf("malformed filter %q", filter)
+	}
+	key, op, value := strings.ToLower(match[1]), match[2], strings.TrimSpace(match[3])
+
+	var err error
+	switch key {
+	case "height":
+		if op != "<=" {
+			return fmt.Errorf("height only supports <=")
+		}
+		s.MaxHeight, err = positive(value)
+	case "fps":
+		if op != "<=" {
+			return fmt.Errorf("fps only supports <=")
+		}
+		s.MaxFPS, err = positive(value)
+	case "filesize":
+		if op != "<=" {
+			return fmt.Errorf("filesize only supports <=")
+		}
+		s.MaxFileSize, err = parseSize(value)
+	case "vcodec", "codec":
+		value = strings.ToLower(value)
+		if op != "=" || codecTags[value] == nil {
+			return fmt.Errorf("vcodec must be one of h264, h265, vp9 or av1")
+		}
+		s.Codec = value
+	case "acodec":
+		value = strings.ToLower(value)
+		if op != "=" || audioCodecTags[value] == nil {
+			return fmt.Errorf("acodec must be one of aac, opus or mp3")
+		}
+		s.AudioCodec = value
+	case "ext", "container":
+		value = strings.ToLower(value)
+		if op != "=" || !containers[value] {
+			return fmt.Errorf("ext must be one of mp4, webm or mov")
+		}
+		s.Container = value
+	case "audio":
+		switch {
+		case op == "":
+			s.RequireAudio = true
+		case op == "=":
+			s.RequireAudio, err = strconv.ParseBool(value)
+		default:
+			return fmt.Errorf("audio only supports =")
+		}
+	case "strict":
+		switch {
+		case op == "":
+			s.Strict = true
+		case op == "=":
+			s.Strict, err = strconv.ParseBool(value)
+		default:
+			return fmt.Errorf("strict only supports =")
+		}
+	case "format_id", "id":
+		if op != "=" || !formatIDPattern.MatchString(value) {
+			return fmt.Errorf("format_id must be a yt-dlp format ID")
+		}
+		s.FormatID = value
+	default:
+		return fmt.Errorf("unknown filter %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %q", key, value)
+	}
+	return nil
+}
+
+// String returns the canonical form of the spec, so that equivalent quality
+// strings such as "720p" and "best[height<=720]" share cache entries
+func (s Spec) String() string {
+	var b strings.Builder
+	b.WriteString(string(s.Prefer))
+	if s.Prefer == "" {
+		b.WriteString(string(Best))
+	}
+	if s.FormatID != "" {
+		fmt.Fprintf(&b, "[format_id=%s]", s.FormatID)
+	}
+	if s.MaxHeight > 0 {
+		fmt.Fprintf(&b, "[height<=%d]", s.MaxHeight)
+	}
+	if s.MaxFPS > 0 {
+		fmt.Fprintf(&b, "[fps<=%d]", s.MaxFPS)
+	}
+	if s.MaxFileSize > 0 {
+		fmt.Fprintf(&b, "[filesize<=%d]", s.MaxFileSize)
+	}
+	if s.Codec != "" {
+		fmt.Fprintf(&b, "[vcodec=%s]", s.Codec)
+	}
+	if s.AudioCodec != "" {
+		fmt.Fprintf(&b, "[acodec=%s]", s.AudioCodec)
+	}
+	if s.Container != "" {
+		fmt.Fprintf(&b, "[ext=%s]", s.Container)
+	}
+	if s.RequireAudio {
+		b.WriteString("[audio]")
+	}
+	if s.Strict {
+		b.WriteString("[strict]")
+	}
+	return b.String()
+}
+
+// Format describes one downloadable format of a video, as listed by yt-dlp
+// or a native extractor
+type Format struct {
+	ID     string
+	URL    string
+	Width  int
+	Height int
+	FPS    float64
+	VCodec string
+	ACodec string
+	Ext    string
+	// FileSize is in bytes, exact or approximate, 0 when unknown
+	FileSize int64
+	// Bitrate is the total bitrate in kbit/s, 0 when unknown
+	Bitrate float64
+	// DynamicRange is "SDR", "HDR10", "HLG", "DV" and so on, "" when unknown
+	DynamicRange string
+}
+
+// HasVideo reports whether the format carries a video stream
+func (f Format) HasVideo() bool {
+	return f.VCodec != "none"
+}
+
+// HasAudio reports whether the format carries an audio stream
+func (f Format) HasAudio() bool {
+	return f.ACodec != "" && f.ACodec != "none"
+}
+
+// HDR reports whether the format uses a high dynamic range transfer
+func (f Format) HDR() bool {
+	return f.DynamicRange != "" && !strings.EqualFold(f.DynamicRange, "SDR")
+}
+
+// Matches reports whether a format satisfies the hard limits of the spec.
+// Unknown fps or file sizes do not disqualify a format.
+func (s Spec) Matches(f Format) bool {
+	if f.URL == "" || !f.HasVideo() {
+		return false
+	}
+	if s.FormatID != "" && f.ID != s.FormatID {
+		return false
+	}
+	if s.MaxHeight > 0 && ShortSide(f.Width, f.Height) > s.MaxHeight {
+		return false
+	}
+	if s.MaxFPS > 0 && f.FPS > float64(s.MaxFPS) {
+		return false
+	}
+	if s.MaxFileSize > 0 && f.FileSize > s.MaxFileSize {
+		return false
+	}
+	if s.RequireAudio && !f.HasAudio() {
+		return false
+	}
+	if s.Strict && !s.Preferred(f) {
+		return false
+	}
+	return true
+}
+
+// Preferred reports whether a format has the preferred codecs and container.
+// The audio codec only applies to formats that carry audio.
+func (s Spec) Preferred(f Format) bool {
+	if s.Container != "" && !strings.EqualFold(f.Ext, s.Container) {
+		return false
+	}
+	if s.Codec != "" && !HasCodec(f.VCodec, s.Codec) {
+		return false
+	}
+	if s.AudioCodec != "" && f.HasAudio() && !HasCodec(f.ACodec, s.AudioCodec) {
+		return false
+	}
+	return true
+}
+
+// HasCodec reports whether a yt-dlp codec tag such as "avc1.64001F" or
+// "mp4a.40.2" is the given video or audio codec name
+func HasCodec(tag string, codec string) bool {
+	prefixes := codecTags[codec]
+	if prefixes == nil {
+		prefixes = audioCodecTags[codec]
+	}
+
+	tag = strings.ToLower(tag)
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(tag, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func positive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("not a positive number: %q", value)
+	}
+	return n, nil
+}
+
+// parseSize parses sizes such as "50M", "512KB" or a plain byte count
+func parseSize(value string) (int64, error) {
+	match := sizePattern.FindStringSubmatch(strings.ToLower(value))
+	if match == nil {
+		return 0, fmt.Errorf("not a size: %q", value)
+	}
+
+	n, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("not a size: %q", value)
+	}
+
+	var shift uint
+	switch strings.TrimRight(match[2], "ib") {
+	case "k":
+		shift = 10
+	case "m":
+		shift = 20
+	case "g":
+		shift = 30
+	}
+	if n > math.MaxInt64>>shift {
+		return 0, fmt.Errorf("size too large: %q", value)
+	}
+	return n << shift, nil
+}
+
+func invalid(spec string, reason string) error {
+	return fmt.Errorf("%w %q: %s", ErrInvalidSpec, spec, reason)
+}
@@ -0,0 +1,73 @@
+package quality
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		quality string
+		want    string
+	}{
+		{"", "best"},
+		{"best", "best"},
+		{" Worst ", "worst"},
+		{"720p", "best[height<=720]"},
+		{"best[height<=720]", "best[height<=720]"},
+		{"best[height<=1080][vcodec=h264][ext=mp4]", "best[height<=1080][vcodec=h264][ext=mp4]"},
+		{"worst[fps<=30,filesize<=50M,audio]", "worst[fps<=30][filesize<=52428800][audio]"},
+		{"best[filesize<=512KB]", "best[filesize<=524288]"},
+		{"best[filesize<=1000]", "best[filesize<=1000]"},
+		{"best[filesize<=8589934591g]", "best[filesize<=" + strconv.FormatInt(8589934591<<30, 10) + "]"},
+		{"best[codec=H265][acodec=aac][container=webm][strict]", "best[vcodec=h265][acodec=aac][ext=webm][strict]"},
+		{"best[id=hd]", "best[format_id=hd]"},
+		{"best[audio=false][strict=false]", "best"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.quality, func(t *testing.T) {
+			spec, err := Parse(tt.quality)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := spec.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.quality, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"0p",
+		"medium",
+		"best[height<=720",
+		"best[height=720]",
+		"best[height<=-1]",
+		"best[fps<=0]",
+		"best[filesize>=50M]",
+		"best[filesize<=0]",
+		"best[filesize<=50T]",
+		"best[filesize<=8589934592g]",
+		"best[filesize<=99999999999g]",
+		"best[filesize<=9007199254740992m]",
+		"best[filesize<=" + strconv.FormatUint(math.MaxInt64+1, 10) + "]",
+		"best[vcodec=mpeg2]",
+		"best[acodec=flac]",
+		"best[ext=mkv]",
+		"best[audio<=1]",
+		"best[format_id=a b]",
+		"best[resolution<=720]",
+	}
+
+	for _, quality := range tests {
+		t.Run(quality, func(t *testing.T) {
+			if spec, err := Parse(quality); !errors.Is(err, ErrInvalidSpec) {
+				t.Errorf("Parse(%q) = %s, %v, want ErrInvalidSpec", quality, spec, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"vidtogallery/internal/models"
+	"vidtogallery/pkg/cache"
+	"vidtogallery/pkg/config"
+)
+
+// Headers sent with every delivery. The signature is an HMAC-SHA256 over
+// "<timestamp>.<body>" keyed with the shared secret, hex encoded and
+// prefixed with "sha256=".
+const (
+	HeaderSignature = "X-VidToGallery-Signature"
+	HeaderTimestamp = "X-VidToGallery-Timestamp"
+	HeaderEvent     = "X-VidToGallery-Event"
+	HeaderDelivery  = "X-VidToGallery-Delivery"
+)
+
+// saveTimeout bounds each write to the delivery log
+const saveTimeout = 5 * time.Second
+
+// maxRetryBackoff caps the exponential backoff between attempts
+const maxRetryBackoff = 10 * time.Minute
+
+var (
+	ErrWebhooksDisabled = errors.New("webhooks are not configured")
+	ErrInvalidCallback  = errors.New("invalid callback URL")
+	ErrHostNotAllowed   = errors.New("callback host is not allowed")
+)
+
+// Notifier delivers signed webhook payloads to allowlisted callback URLs,
+// retrying with exponential backoff and recording every attempt
+type Notifier struct {
+	client       *http.Client
+	secret       []byte
+	allowedHosts []string
+	maxAttempts  int
+	backoff      time.Duration
+	cacheService *cache.Service
+	logger       *logrus.Logger
+
+	// ctx is cancelled by Shutdown to interrupt deliveries still running
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	inflight int
+	// idle is closed whenever no delivery is in flight
+	idle chan struct{}
+}
+
+func NewNotifier(cfg *config.Config, cacheService *cache.Service, logger *logrus.Logger) *Notifier {
+	maxAttempts := cfg.Webhooks.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	allowedHosts := make([]string, len(cfg.Webhooks.AllowedHosts))
+	for i, host := range cfg.Webhooks.AllowedHosts {
+		allowedHosts[i] = strings.ToLower(host)
+	}
+
+	idle := make(chan struct{})
+	close(idle)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Notifier{
+		client: &http.Client{
+			Timeout: cfg.Webhooks.Timeout,
+			// Following redirects could leave the allowlist
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		secret:       []byte(cfg.Webhooks.Secret),
+		allowedHosts: allowedHosts,
+		maxAttempts:  maxAttempts,
+		backoff:      cfg.Webhooks.Backoff,
+		cacheService: cacheService,
+		logger:       logger,
+		ctx:          ctx,
+		cancel:       cancel,
+		idle:         idle,
+	}
+}
+
+// Enabled reports whether a secret and at least one allowed host are configured
+func (n *Notifier) Enabled() bool {
+	return len(n.secret) > 0 && len(n.allowedHosts) > 0
+}
+
+// ValidateURL checks that callbackURL is an http(s) URL on an allowed host.
+// Allowed hosts match the hostname, the host with port, or with a "*."
+// prefix any subdomain.
+func (n *Notifier) ValidateURL(callbackURL string) error {
+	if !n.Enabled() {
+		return ErrWebhooksDisabled
+	}
+
+	u, err := url.Parse(callbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidCallback, callbackURL)
+	}
+
+	hostname := strings.ToLower(u.Hostname())
+	host := strings.ToLower(u.Host)
+	for _, allowed := range n.allowedHosts {
+		if strings.HasPrefix(allowed, "*.") {
+			if strings.HasSuffix(hostname, allowed[1:]) {
+				return nil
+			}
+			continue
+		}
+		if hostname == allowed || host == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrHostNotAllowed, hostname)
+}
+
+// Sign returns the signature header value for body sent at timestamp
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature matches body sent at timestamp
+func Verify(secret []byte, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Deliver sends payload to callbackURL in the background. callbackURL must
+// have passed ValidateURL.
+func (n *Notifier) Deliver(callbackURL string, payload models.WebhookPayload) {
+	now := time.Now()
+	payload.SentAt = now
+
+	delivery := &models.WebhookDelivery{
+		ID:        uuid.NewString(),
+		Event:     payload.Event,
+		URL:       callbackURL,
+		JobID:     payload.JobID,
+		BatchID:   payload.BatchID,
+		State:     models.DeliveryStatePending,
+		Attempts:  []models.WebhookAttempt{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	n.save(delivery, true)
+
+	n.mu.Lock()
+	if n.inflight == 0 {
+		n.idle = make(chan struct{})
+	}
+	n.inflight++
+	n.mu.Unlock()
+
+	go func() {
+		defer n.finish()
+		n.deliver(delivery, payload)
+	}()
+}
+
+func (n *Notifier) finish() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.inflight--
+	if n.inflight == 0 {
+		close(n.idle)
+	}
+}
+
+// Shutdown waits for in-flight deliveries, including their retries, until
+// ctx ends. Deliveries still running then are interrupted and stay pending
+// in the delivery log.
+func (n *Notifier) Shutdown(ctx context.Context) error {
+	n.mu.Lock()
+	idle := n.idle
+	n.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	default:
+	}
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+	}
+
+	n.cancel()
+	select {
+	case <-idle:
+	case <-time.After(saveTimeout):
+		n.logger.Warn("Interrupted webhook deliveries did not exit")
+	}
+	return ctx.Err()
+}
+
+func (n *Notifier) deliver(delivery *models.WebhookDelivery, payload models.WebhookPayload) {
+	logger := n.logger.WithFields(logrus.Fields{
+		"delivery_id": delivery.ID,
+		"event":       delivery.Event,
+		"url":         delivery.URL,
+	})
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logger.WithError(err).Error("Failed to encode webhook payload")
+		delivery.State = models.DeliveryStateFailed
+		n.save(delivery, false)
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, retryable := n.attempt(delivery, body)
+		if n.ctx.Err() != nil {
+			// Interrupted by shutdown, so the attempt says nothing about the receiver
+			delivery.NextAttempt = nil
+			n.save(delivery, false)
+			logger.WithField("attempts", attempt-1).Warn("Webhook delivery interrupted by shutdown")
+			return
+		}
+		delivery.Attempts = append(delivery.Attempts, result)
+		delivery.NextAttempt = nil
+
+		if result.Error == "" {
+			delivery.State = models.DeliveryStateDelivered
+			n.save(delivery, false)
+			logger.WithField("attempts", attempt).Info("Webhook delivered")
+			return
+		}
+
+		if !retryable || attempt >= n.maxAttempts {
+			delivery.State = models.DeliveryStateFailed
+			n.save(delivery, false)
+			logger.WithField("attempts", attempt).WithField("error", result.Error).Warn("Webhook delivery failed")
+			return
+		}
+
+		wait := n.retryBackoff(attempt)
+		next := time.Now().Add(wait)
+		delivery.NextAttempt = &next
+		n.save(delivery, false)
+		logger.WithField("attempt", attempt).WithField("retry_in", wait).Debug("Webhook attempt failed, retrying")
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-n.ctx.Done():
+			timer.Stop()
+			delivery.NextAttempt = nil
+			n.save(delivery, false)
+			logger.WithField("attempts", attempt).Warn("Webhook delivery interrupted by shutdown")
+			return
+		}
+	}
+}
+
+// retryBackoff is the wait after a failed attempt, doubling each time up
+// to maxRetryBackoff
+func (n *Notifier) retryBackoff(attempt int) time.Duration {
+	wait := n.backoff
+	for i := 1; i < attempt && wait < maxRetryBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxRetryBackoff)
+}
+
+// attempt POSTs body once and reports whether a failure is worth retrying
+func (n *Notifier) attempt(delivery *models.WebhookDelivery, body []byte) (models.WebhookAttempt, bool) {
+	start := time.Now()
+	result := models.WebhookAttempt{At: start}
+
+	request, err := http.NewRequestWithContext(n.ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		result.Error = err.Error()
+		return result, false
+	}
+
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "VidToGallery-Webhook/1.0")
+	request.Header.Set(HeaderTimestamp, timestamp)
+	request.Header.Set(HeaderSignature, Sign(n.secret, timestamp, body))
+	request.Header.Set(HeaderEvent, delivery.Event)
+	request.Header.Set(HeaderDelivery, delivery.ID)
+
+	response, err := n.client.Do(request)
+	result.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result, true
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+
+	result.StatusCode = response.StatusCode
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return result, false
+	}
+
+	result.Error = fmt.Sprintf("HTTP %d", response.StatusCode)
+	retryable := response.StatusCode >= 500 ||
+		response.StatusCode == http.StatusTooManyRequests ||
+		response.StatusCode == http.StatusRequestTimeout
+	return result, retryable
+}
+
+// save writes the delivery to the delivery log
+func (n *Notifier) save(delivery *models.WebhookDelivery, created bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+	defer cancel()
+
+	delivery.UpdatedAt = time.Now()
+
+	var err error
+	if created {
+		err = n.cacheService.AddWebhookDelivery(ctx, delivery)
+	} else {
+		err = n.cacheService.UpdateWebhookDelivery(ctx, delivery)
+	}
+	if err != nil && !errors.Is(err, cache.ErrCacheDisabled) {
+		n.logger.WithError(err).WithField("delivery_id", delivery.ID).Warn("Failed to record webhook delivery")
+	}
+}